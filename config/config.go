@@ -27,12 +27,22 @@ func InitEnvironment(confPath string) error {
 }
 
 type Environment struct {
-	Core         sectionCore
-	Log          sectionLog
-	MySQL        sectionMySQL
-	Redis        sectionRedis
-	SessionAuth  sectionSessionAuth
-	SectionImage sectionImage
+	Core          sectionCore
+	Log           sectionLog
+	MySQL         sectionMySQL
+	Redis         sectionRedis
+	Auth          sectionAuth
+	SessionAuth   sectionSessionAuth
+	JWT           sectionJWT
+	OIDC          sectionOIDC
+	SectionImage  sectionImage
+	Notifier      sectionNotifier
+	ResetPassword sectionResetPassword
+	Lockout       sectionLockout
+	TwoFactor     sectionTwoFactor
+	Password      sectionPassword
+	AuditLog      sectionAuditLog
+	Entity        sectionEntity
 }
 
 type sectionCore struct {
@@ -60,15 +70,124 @@ type sectionRedis struct {
 	Port     string
 	Password string
 }
+type sectionAuth struct {
+	Strategy string // session, jwt
+}
+
 type sectionSessionAuth struct {
+	Provider    string // redis, memory
 	Name        string
 	MaxLifeTime uint
+	MaxSessions uint
+	// ResetTokenTTL is the lifetime of a password reset token in seconds
+	ResetTokenTTL uint
+	// CookieSecure sends the cookies over HTTPS only, turned off for plain HTTP development
+	CookieSecure bool
+}
+
+type sectionJWT struct {
+	Algorithm string // HS256, RS256
+	// Secret signs HS256 tokens, at least 32 bytes
+	Secret string
+	// PrivateKeyPath is the PEM file of the RS256 key, published as KeyID in /.well-known/jwks.json
+	PrivateKeyPath string
+	KeyID          string
+	Issuer         string
+	// Token lifetimes in seconds
+	AccessTokenTTL  uint
+	RefreshTokenTTL uint
+}
+
+// sectionOIDC configures single sign-on with an OpenID Connect identity provider, disabled without IssuerURL
+type sectionOIDC struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback route registered at the provider, ending with /api/v1/auth/oidc/callback
+	RedirectURL string
+	Scopes      []string
+	// AutoProvision creates a user signing in for the first time without a matching email
+	AutoProvision bool
+	// GroupsClaim names the ID token claim listing the user's groups, mapped by GroupMappings
+	GroupsClaim string
+	// GroupMappings are comma separated "group:divisionID" or "group:divisionID:role"
+	GroupMappings string
 }
 
 type sectionImage struct {
 	Size int64
 }
 
+type sectionNotifier struct {
+	Driver       string // smtp, file; required
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+	// FilePath of the file driver, the messages are logged when empty, in the debug and test modes only
+	FilePath string
+}
+
+// maxLockoutDelay is the longest delay of a failed login in milliseconds, as each delayed response holds its connection
+const maxLockoutDelay = 2000
+
+// sectionLockout is the brute-force protection of login, durations in seconds
+type sectionLockout struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailureWindow      uint
+	Duration           uint
+	// Progressive delay of a failed login: DelayStep milliseconds per recent failure, up to MaxDelay,
+	// which is capped at maxLockoutDelay to answer well within the server's 5 second write timeout
+	DelayStep uint
+	MaxDelay  uint
+}
+
+type sectionTwoFactor struct {
+	// Issuer shown by the authenticator app
+	Issuer string
+	// PreAuthLifeTime is how long a login may wait for its second factor, in seconds
+	PreAuthLifeTime uint
+}
+
+type sectionPassword struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is the number of latest passwords that can not be reused, 0 to allow reuse
+	HistorySize int
+	// BlocklistPath is the file of common passwords to reject, one per line
+	BlocklistPath string
+	// HashAlgorithm hashes new passwords, argon2id or bcrypt; hashes of the other one are upgraded on login
+	HashAlgorithm     string
+	BcryptCost        int
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+type sectionAuditLog struct {
+	// RetentionDays is how long audit logs are kept, 0 to keep them forever
+	RetentionDays int
+}
+
+type sectionEntity struct {
+	// TrashRetentionDays is how long soft-deleted entities are kept, 0 to keep them forever
+	TrashRetentionDays int
+}
+
+type sectionResetPassword struct {
+	// URL of the frontend reset page, the token is appended as the resetToken query
+	URL string
+	// Rates of self-service requests, in the limiter format such as "3-H"
+	AccountRate string
+	IPRate      string
+}
+
 func loadEnvironment(path string) (Environment, error) {
 	var env Environment
 
@@ -121,12 +240,204 @@ func loadEnvironment(path string) (Environment, error) {
 	env.Redis.Port = viper.GetString("redis_port")
 	env.Redis.Password = viper.GetString("redis_password")
 
+	// auth
+	env.Auth.Strategy = viper.GetString("auth_strategy")
+	if len(env.Auth.Strategy) == 0 {
+		env.Auth.Strategy = "session"
+	}
+	// An unknown strategy would fall back to the session silently, leaving the jwt routes unusable
+	switch env.Auth.Strategy {
+	case "session", "jwt":
+	default:
+		return Environment{}, fmt.Errorf("AUTH_STRATEGY must be session or jwt, got %q", env.Auth.Strategy)
+	}
+
 	// session auth
+	env.SessionAuth.Provider = viper.GetString("session_auth_provider")
+	if len(env.SessionAuth.Provider) == 0 {
+		env.SessionAuth.Provider = "redis"
+	}
 	env.SessionAuth.Name = viper.GetString("session_auth_name")
 	env.SessionAuth.MaxLifeTime = viper.GetUint("session_auth_max_life_time")
+	env.SessionAuth.MaxSessions = viper.GetUint("session_auth_max_sessions")
+	env.SessionAuth.ResetTokenTTL = viper.GetUint("session_auth_reset_token_ttl")
+	if env.SessionAuth.ResetTokenTTL == 0 {
+		env.SessionAuth.ResetTokenTTL = 900
+	}
+	env.SessionAuth.CookieSecure = true
+	if viper.IsSet("session_auth_cookie_secure") {
+		env.SessionAuth.CookieSecure = viper.GetBool("session_auth_cookie_secure")
+	}
+
+	// jwt
+	env.JWT.Algorithm = viper.GetString("jwt_algorithm")
+	if len(env.JWT.Algorithm) == 0 {
+		env.JWT.Algorithm = "HS256"
+	}
+	env.JWT.Secret = viper.GetString("jwt_secret")
+	env.JWT.PrivateKeyPath = viper.GetString("jwt_private_key_path")
+	env.JWT.KeyID = viper.GetString("jwt_key_id")
+	env.JWT.Issuer = viper.GetString("jwt_issuer")
+	if len(env.JWT.Issuer) == 0 {
+		env.JWT.Issuer = "go-ddd-example"
+	}
+	env.JWT.AccessTokenTTL = viper.GetUint("jwt_access_token_ttl")
+	if env.JWT.AccessTokenTTL == 0 {
+		env.JWT.AccessTokenTTL = 900
+	}
+	env.JWT.RefreshTokenTTL = viper.GetUint("jwt_refresh_token_ttl")
+	if env.JWT.RefreshTokenTTL == 0 {
+		env.JWT.RefreshTokenTTL = 1209600
+	}
+
+	// oidc
+	env.OIDC.IssuerURL = viper.GetString("oidc_issuer_url")
+	env.OIDC.ClientID = viper.GetString("oidc_client_id")
+	env.OIDC.ClientSecret = viper.GetString("oidc_client_secret")
+	env.OIDC.RedirectURL = viper.GetString("oidc_redirect_url")
+	env.OIDC.Scopes = strings.Fields(viper.GetString("oidc_scopes"))
+	if len(env.OIDC.Scopes) == 0 {
+		env.OIDC.Scopes = []string{"openid", "email", "profile"}
+	}
+	env.OIDC.AutoProvision = true
+	if viper.IsSet("oidc_auto_provision") {
+		env.OIDC.AutoProvision = viper.GetBool("oidc_auto_provision")
+	}
+	env.OIDC.GroupsClaim = viper.GetString("oidc_groups_claim")
+	env.OIDC.GroupMappings = viper.GetString("oidc_group_mappings")
 
 	// image
 	env.SectionImage.Size = viper.GetInt64("image_size")
 
+	// notifier
+	env.Notifier.Driver = viper.GetString("notifier_driver")
+	env.Notifier.SMTPHost = viper.GetString("notifier_smtp_host")
+	env.Notifier.SMTPPort = viper.GetString("notifier_smtp_port")
+	if len(env.Notifier.SMTPPort) == 0 {
+		env.Notifier.SMTPPort = "587"
+	}
+	env.Notifier.SMTPUser = viper.GetString("notifier_smtp_user")
+	env.Notifier.SMTPPassword = viper.GetString("notifier_smtp_password")
+	env.Notifier.From = viper.GetString("notifier_from")
+	env.Notifier.FilePath = viper.GetString("notifier_file_path")
+	// The messages carry live reset links, which must not leak into the logs of a deployment
+	switch env.Notifier.Driver {
+	case "smtp":
+	case "file":
+		if env.Notifier.FilePath == "" && env.Core.Mode != "debug" && env.Core.Mode != "test" {
+			return Environment{}, fmt.Errorf("NOTIFIER_FILE_PATH is required by the file notifier outside the debug and test modes")
+		}
+	default:
+		return Environment{}, fmt.Errorf("NOTIFIER_DRIVER must be smtp or file, got %q", env.Notifier.Driver)
+	}
+
+	// lockout
+	env.Lockout.MaxAccountFailures = viper.GetInt("lockout_max_account_failures")
+	if env.Lockout.MaxAccountFailures == 0 {
+		env.Lockout.MaxAccountFailures = 5
+	}
+	env.Lockout.MaxIPFailures = viper.GetInt("lockout_max_ip_failures")
+	if env.Lockout.MaxIPFailures == 0 {
+		env.Lockout.MaxIPFailures = 20
+	}
+	env.Lockout.FailureWindow = viper.GetUint("lockout_failure_window")
+	if env.Lockout.FailureWindow == 0 {
+		env.Lockout.FailureWindow = 900
+	}
+	env.Lockout.Duration = viper.GetUint("lockout_duration")
+	if env.Lockout.Duration == 0 {
+		env.Lockout.Duration = 900
+	}
+	env.Lockout.DelayStep = viper.GetUint("lockout_delay_step")
+	if env.Lockout.DelayStep == 0 {
+		env.Lockout.DelayStep = 500
+	}
+	env.Lockout.MaxDelay = viper.GetUint("lockout_max_delay")
+	if env.Lockout.MaxDelay == 0 || env.Lockout.MaxDelay > maxLockoutDelay {
+		env.Lockout.MaxDelay = maxLockoutDelay
+	}
+
+	// two-factor
+	env.TwoFactor.Issuer = viper.GetString("two_factor_issuer")
+	if len(env.TwoFactor.Issuer) == 0 {
+		env.TwoFactor.Issuer = "go-ddd-example"
+	}
+	env.TwoFactor.PreAuthLifeTime = viper.GetUint("two_factor_pre_auth_life_time")
+	if env.TwoFactor.PreAuthLifeTime == 0 {
+		env.TwoFactor.PreAuthLifeTime = 300
+	}
+
+	// password policy
+	env.Password.MinLength = viper.GetInt("password_min_length")
+	if env.Password.MinLength == 0 {
+		env.Password.MinLength = 8
+	}
+	env.Password.MaxLength = viper.GetInt("password_max_length")
+	if env.Password.MaxLength == 0 {
+		env.Password.MaxLength = 64
+	}
+	env.Password.RequireUpper, env.Password.RequireLower, env.Password.RequireDigit = true, true, true
+	if viper.IsSet("password_require_upper") {
+		env.Password.RequireUpper = viper.GetBool("password_require_upper")
+	}
+	if viper.IsSet("password_require_lower") {
+		env.Password.RequireLower = viper.GetBool("password_require_lower")
+	}
+	if viper.IsSet("password_require_digit") {
+		env.Password.RequireDigit = viper.GetBool("password_require_digit")
+	}
+	env.Password.RequireSymbol = viper.GetBool("password_require_symbol")
+	env.Password.HistorySize = 5
+	if viper.IsSet("password_history_size") {
+		env.Password.HistorySize = viper.GetInt("password_history_size")
+	}
+	env.Password.BlocklistPath = viper.GetString("password_blocklist_path")
+	if len(env.Password.BlocklistPath) == 0 {
+		env.Password.BlocklistPath = "common_passwords.txt"
+	}
+	env.Password.HashAlgorithm = viper.GetString("password_hash_algorithm")
+	if len(env.Password.HashAlgorithm) == 0 {
+		env.Password.HashAlgorithm = "argon2id"
+	}
+	env.Password.BcryptCost = viper.GetInt("password_bcrypt_cost")
+	if env.Password.BcryptCost == 0 {
+		env.Password.BcryptCost = 12
+	}
+	env.Password.Argon2Memory = viper.GetUint32("password_argon2_memory")
+	if env.Password.Argon2Memory == 0 {
+		env.Password.Argon2Memory = 64 * 1024
+	}
+	env.Password.Argon2Iterations = viper.GetUint32("password_argon2_iterations")
+	if env.Password.Argon2Iterations == 0 {
+		env.Password.Argon2Iterations = 3
+	}
+	env.Password.Argon2Parallelism = uint8(viper.GetUint("password_argon2_parallelism"))
+	if env.Password.Argon2Parallelism == 0 {
+		env.Password.Argon2Parallelism = 2
+	}
+
+	// audit log
+	env.AuditLog.RetentionDays = 365
+	if viper.IsSet("audit_log_retention_days") {
+		env.AuditLog.RetentionDays = viper.GetInt("audit_log_retention_days")
+	}
+
+	// entity
+	env.Entity.TrashRetentionDays = 30
+	if viper.IsSet("entity_trash_retention_days") {
+		env.Entity.TrashRetentionDays = viper.GetInt("entity_trash_retention_days")
+	}
+
+	// reset password
+	env.ResetPassword.URL = viper.GetString("reset_password_url")
+	env.ResetPassword.AccountRate = viper.GetString("reset_password_account_rate")
+	if len(env.ResetPassword.AccountRate) == 0 {
+		env.ResetPassword.AccountRate = "3-H"
+	}
+	env.ResetPassword.IPRate = viper.GetString("reset_password_ip_rate")
+	if len(env.ResetPassword.IPRate) == 0 {
+		env.ResetPassword.IPRate = "10-H"
+	}
+
 	return env, nil
 }
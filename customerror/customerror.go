@@ -74,6 +74,86 @@ var (
 		Code:     10013,
 		Message:  "Invalid email",
 	}
+	InvalidCSRFToken = mGin.CustomError{
+		HTTPCode: http.StatusForbidden,
+		Code:     10014,
+		Message:  "Invalid CSRF token",
+	}
+	InvalidResetToken = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10015,
+		Message:  "Invalid or expired reset token",
+	}
+	TooManyRequests = mGin.CustomError{
+		HTTPCode: http.StatusTooManyRequests,
+		Code:     10016,
+		Message:  "Too many requests, please try again later",
+	}
+	AccountLocked = mGin.CustomError{
+		HTTPCode: http.StatusLocked,
+		Code:     10017,
+		Message:  "Too many failed logins, account temporarily locked",
+	}
+	InvalidTwoFactorCode = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10018,
+		Message:  "Invalid two-factor code",
+	}
+	InvalidPreAuthToken = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10019,
+		Message:  "Invalid or expired pre-auth token",
+	}
+	TwoFactorRequired = mGin.CustomError{
+		HTTPCode: http.StatusForbidden,
+		Code:     10020,
+		Message:  "Two-factor authentication is required",
+	}
+	TwoFactorNotEnrolled = mGin.CustomError{
+		HTTPCode: http.StatusConflict,
+		Code:     10021,
+		Message:  "Two-factor authentication is not set up",
+	}
+	TwoFactorEnabled = mGin.CustomError{
+		HTTPCode: http.StatusConflict,
+		Code:     10022,
+		Message:  "Two-factor authentication is already enabled",
+	}
+	InvalidAccessToken = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10023,
+		Message:  "Invalid or expired access token",
+	}
+	InvalidRefreshToken = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10024,
+		Message:  "Invalid or expired refresh token",
+	}
+	InvalidAPIKey = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10025,
+		Message:  "Invalid or expired API key",
+	}
+	InvalidAPIKeyScope = mGin.CustomError{
+		HTTPCode: http.StatusNotAcceptable,
+		Code:     10026,
+		Message:  "API key scope is not an available resource action",
+	}
+	InvalidOIDCLogin = mGin.CustomError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     10027,
+		Message:  "Invalid or expired single sign-on login",
+	}
+	OIDCDisabled = mGin.CustomError{
+		HTTPCode: http.StatusNotFound,
+		Code:     10028,
+		Message:  "Single sign-on is not configured",
+	}
+	OIDCLinkRequired = mGin.CustomError{
+		HTTPCode: http.StatusConflict,
+		Code:     10029,
+		Message:  "An account of this email exists, sign in to it and link single sign-on first",
+	}
 	// functions
 	RecordNotFound = mGin.CustomError{
 		HTTPCode: http.StatusNotFound,
@@ -137,6 +217,11 @@ var (
 		Code:     30012,
 		Message:  "Some records not found",
 	}
+	VersionConflict = mGin.CustomError{
+		HTTPCode: http.StatusConflict,
+		Code:     30013,
+		Message:  "Record has been changed by another request",
+	}
 )
 
 func BitsToKB(bits int64) float64 {
@@ -0,0 +1,17 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var announcementMigration = &gormigrate.Migration{
+	ID: "announcementMigration",
+	Migrate: func(db *gorm.DB) error {
+		return db.AutoMigrate(&model.Announcement{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&model.Announcement{})
+	},
+}
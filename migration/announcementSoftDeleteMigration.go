@@ -0,0 +1,22 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var announcementSoftDeleteMigration = &gormigrate.Migration{
+	ID: "announcementSoftDeleteMigration",
+	Migrate: func(db *gorm.DB) error {
+		// Adds the Announcement deleted_at column
+		return db.AutoMigrate(&model.Announcement{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		// The soft-deleted announcements would come back
+		if err := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&model.Announcement{}).Error; err != nil {
+			return err
+		}
+		return db.Migrator().DropColumn(&model.Announcement{}, "deleted_at")
+	},
+}
@@ -0,0 +1,18 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var announcementVersionMigration = &gormigrate.Migration{
+	ID: "announcementVersionMigration",
+	Migrate: func(db *gorm.DB) error {
+		// Adds the Announcement version column
+		return db.AutoMigrate(&model.Announcement{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropColumn(&model.Announcement{}, "version")
+	},
+}
@@ -0,0 +1,17 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var apiKeyMigration = &gormigrate.Migration{
+	ID: "apiKeyMigration",
+	Migrate: func(db *gorm.DB) error {
+		return db.AutoMigrate(&model.APIKey{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&model.APIKey{})
+	},
+}
@@ -0,0 +1,21 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// casbinDomainMigration moves the "sub, obj, act" policies to "sub, dom, obj, act",
+// using the division itself as the domain
+var casbinDomainMigration = &gormigrate.Migration{
+	ID: "casbinDomainMigration",
+	Migrate: func(db *gorm.DB) error {
+		if !db.Migrator().HasTable("casbin_rules") {
+			return nil
+		}
+		return db.Exec("UPDATE casbin_rules SET v3 = v2, v2 = v1, v1 = v0 WHERE ptype = 'p' AND (v3 = '' OR v3 IS NULL)").Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("UPDATE casbin_rules SET v1 = v2, v2 = v3, v3 = '' WHERE ptype = 'p' AND v0 = v1").Error
+	},
+}
@@ -0,0 +1,18 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var divisionMigration = &gormigrate.Migration{
+	ID: "divisionMigration",
+	Migrate: func(db *gorm.DB) error {
+		// User.Divisions creates the user_divisions join table
+		return db.AutoMigrate(&model.Division{}, &model.User{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("user_divisions", &model.Division{})
+	},
+}
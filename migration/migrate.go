@@ -10,6 +10,16 @@ import (
 
 var migrations = []*gormigrate.Migration{
 	firstMigration,
+	divisionMigration,
+	casbinDomainMigration,
+	twoFactorMigration,
+	apiKeyMigration,
+	userIdentityMigration,
+	passwordHistoryMigration,
+	auditLogMigration,
+	announcementMigration,
+	announcementVersionMigration,
+	announcementSoftDeleteMigration,
 }
 
 // New new migration
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var twoFactorMigration = &gormigrate.Migration{
+	ID: "twoFactorMigration",
+	Migrate: func(db *gorm.DB) error {
+		// Adds the User totp columns
+		return db.AutoMigrate(&model.User{}, &model.UserRecoveryCode{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		if err := db.Migrator().DropTable(&model.UserRecoveryCode{}); err != nil {
+			return err
+		}
+		for _, column := range []string{"totp_secret", "totp_enabled", "totp_required", "totp_last_step"} {
+			if err := db.Migrator().DropColumn(&model.User{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
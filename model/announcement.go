@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/filters"
+	"gorm.io/gorm"
+)
+
+// Announcement is a notice shown to the users, served by the generic entity routes
+type Announcement struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Title   string `json:"title" gorm:"size:128;not null"`
+	Content string `json:"content" gorm:"type:text"`
+	Version uint   `json:"version" gorm:"not null;default:1"`
+
+	CreatedAt time.Time      `json:"createdAt" gorm:"index"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+}
+
+func (a Announcement) ModelName() string {
+	return "announcement"
+}
+
+func (a Announcement) GetID() uint {
+	return a.ID
+}
+
+func (a Announcement) GetVersion() uint {
+	return a.Version
+}
+
+func (a *Announcement) SetVersion(version uint) {
+	a.Version = version
+}
+
+// AnnouncementFilter selects announcements by the query string filters, e.g. title[like]=maintenance
+type AnnouncementFilter struct {
+	Title     *filters.StringFilter    `mTag:"title"`
+	CreatedAt *filters.TimestampFilter `mTag:"createdAt"`
+}
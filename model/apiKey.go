@@ -0,0 +1,99 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// APIKeyTokenPrefix marks an API key in the Authorization header, telling it apart from other bearer tokens
+const APIKeyTokenPrefix = "gdk_"
+
+// APIKey is a long-lived credential of a machine client, acting as its owner within its scopes.
+// The token is "gdk_<prefix>_<secret>": the prefix finds the key, and only the hash of the secret is stored.
+type APIKey struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	UserID     uint          `json:"userId" gorm:"index;not null"`
+	Name       string        `json:"name" gorm:"size:64;not null"`
+	Prefix     string        `json:"prefix" gorm:"size:16;uniqueIndex;not null"`
+	SecretHash string        `json:"-" gorm:"size:64;not null"`
+	Scopes     []APIKeyScope `json:"scopes" gorm:"serializer:json;type:text;not null"`
+	ExpiresAt  *time.Time    `json:"expiresAt"`
+	LastUsedAt *time.Time    `json:"lastUsedAt"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// APIKeyScope is a resource action of resource_action_rules.json the key may perform
+type APIKeyScope struct {
+	Resource Resource `json:"resource" binding:"required"`
+	Action   Action   `json:"action" binding:"required"`
+}
+
+// NewAPIKeyToken returns a new token with its prefix and secret hash
+func NewAPIKeyToken() (token, prefix, secretHash string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", errors.Wrap(err, "rand.Read")
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", errors.Wrap(err, "rand.Read")
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return APIKeyTokenPrefix + prefix + "_" + secret, prefix, hashAPIKeySecret(secret), nil
+}
+
+// IsAPIKeyToken tells whether the bearer token is an API key
+func IsAPIKeyToken(token string) bool {
+	return strings.HasPrefix(token, APIKeyTokenPrefix)
+}
+
+// ParseAPIKeyToken splits the token into its prefix and secret
+func ParseAPIKeyToken(token string) (prefix, secret string, ok bool) {
+	rest, found := strings.CutPrefix(token, APIKeyTokenPrefix)
+	if !found {
+		return "", "", false
+	}
+	prefix, secret, found = strings.Cut(rest, "_")
+	if !found || prefix == "" || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecret tells whether the secret is the key's
+func (k APIKey) VerifySecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(k.SecretHash)) == 1
+}
+
+// IsExpired tells whether the key is past its expiry, a key without one never expires
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Allows tells whether the scopes cover the resource action.
+// Routes without a resource, such as the account settings, are out of every scope.
+func (k APIKey) Allows(resource Resource, action Action) bool {
+	if resource == "" {
+		return false
+	}
+	for _, scope := range k.Scopes {
+		if scope.Resource == resource && scope.Action == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAPIKeyToken(t *testing.T) {
+	token, prefix, _, err := NewAPIKeyToken()
+	assert.NoError(t, err)
+	assert.True(t, IsAPIKeyToken(token))
+
+	parsedPrefix, secret, ok := ParseAPIKeyToken(token)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsedPrefix)
+	assert.NotEmpty(t, secret)
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "no marker", token: "abc123_secret"},
+		{name: "no separator", token: APIKeyTokenPrefix + "abc123secret"},
+		{name: "empty prefix", token: APIKeyTokenPrefix + "_secret"},
+		{name: "empty secret", token: APIKeyTokenPrefix + "abc123_"},
+		{name: "marker only", token: APIKeyTokenPrefix},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix, secret, ok := ParseAPIKeyToken(tc.token)
+			assert.False(t, ok)
+			assert.Empty(t, prefix)
+			assert.Empty(t, secret)
+		})
+	}
+}
+
+func TestAPIKeyVerifySecret(t *testing.T) {
+	token, _, secretHash, err := NewAPIKeyToken()
+	assert.NoError(t, err)
+	_, secret, ok := ParseAPIKeyToken(token)
+	assert.True(t, ok)
+
+	key := APIKey{SecretHash: secretHash}
+	assert.True(t, key.VerifySecret(secret))
+	assert.False(t, key.VerifySecret(secret+"x"))
+	assert.False(t, key.VerifySecret(""))
+	assert.False(t, APIKey{}.VerifySecret(secret))
+}
+
+func TestAPIKeyIsExpired(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	assert.False(t, APIKey{}.IsExpired(now), "no expiry")
+	assert.False(t, APIKey{ExpiresAt: &future}.IsExpired(now))
+	assert.True(t, APIKey{ExpiresAt: &past}.IsExpired(now))
+	assert.True(t, APIKey{ExpiresAt: &now}.IsExpired(now), "expires at the instant")
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	key := APIKey{Scopes: []APIKeyScope{
+		{Resource: ResourceUser, Action: ActionRead},
+		{Resource: ResourceDivision, Action: ActionUpdate},
+	}}
+
+	testCases := []struct {
+		name     string
+		resource Resource
+		action   Action
+		allows   bool
+	}{
+		{name: "scope", resource: ResourceUser, action: ActionRead, allows: true},
+		{name: "other scope", resource: ResourceDivision, action: ActionUpdate, allows: true},
+		{name: "action mismatch", resource: ResourceUser, action: ActionUpdate},
+		{name: "resource mismatch", resource: ResourceDivision, action: ActionRead},
+		{name: "empty resource", resource: "", action: ActionRead},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.allows, key.Allows(tc.resource, tc.action))
+		})
+	}
+
+	assert.False(t, APIKey{}.Allows(ResourceUser, ActionRead), "no scopes")
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/filters"
+	"github.com/pkg/errors"
+)
+
+// AuditLog is an append-only record of a security relevant or data changing action
+type AuditLog struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	ActorID   uint                   `json:"actorId" gorm:"index;not null;default:0"` // 0 when no one is signed in
+	Action    string                 `json:"action" gorm:"size:64;index;not null"`
+	Resource  string                 `json:"resource" gorm:"size:64;index;not null;default:''"`
+	TargetID  string                 `json:"targetId" gorm:"size:64;not null;default:''"`
+	Changes   map[string]AuditChange `json:"changes,omitempty" gorm:"serializer:json;type:text"`
+	IP        string                 `json:"ip" gorm:"size:64;not null;default:''"`
+	RequestID string                 `json:"requestId" gorm:"size:64;index;not null;default:''"`
+	CreatedAt time.Time              `json:"createdAt" gorm:"index"`
+}
+
+// AuditChange is the value of a field before and after the action
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLogFilter selects audit logs by the query string filters, e.g. action[in]=user.create,user.delete
+type AuditLogFilter struct {
+	ActorID   *filters.NumberFilter    `mTag:"actorId"`
+	Action    *filters.StringFilter    `mTag:"action"`
+	Resource  *filters.StringFilter    `mTag:"resource"`
+	TargetID  *filters.StringFilter    `mTag:"targetId"`
+	RequestID *filters.StringFilter    `mTag:"requestId"`
+	CreatedAt *filters.TimestampFilter `mTag:"createdAt"`
+}
+
+// AuditDiff returns the JSON fields which differ between before and after, every field for a nil before or after.
+// Fields hidden from JSON, such as password hashes, are never recorded.
+func AuditDiff(before, after interface{}) (map[string]AuditChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]AuditChange)
+	for key, value := range beforeFields {
+		if afterValue, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[key] = AuditChange{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = AuditChange{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
+
+func auditFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "json.Marshal")
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.Wrap(err, "json.Unmarshal")
+	}
+	// Timestamps change on every update
+	delete(fields, "updatedAt")
+	return fields, nil
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type auditSubject struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Password  string `json:"-"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+func TestAuditDiff(t *testing.T) {
+	testCases := []struct {
+		name    string
+		before  interface{}
+		after   interface{}
+		changes map[string]AuditChange
+	}{
+		{
+			name:    "nil before and after",
+			changes: nil,
+		},
+		{
+			name:  "nil before records every field as added",
+			after: auditSubject{Name: "a", Email: "a@example.com", Password: "hash", UpdatedAt: 1},
+			changes: map[string]AuditChange{
+				"name":  {After: "a"},
+				"email": {After: "a@example.com"},
+			},
+		},
+		{
+			name:   "nil after records every field as removed",
+			before: auditSubject{Name: "a", Email: "a@example.com", Password: "hash", UpdatedAt: 1},
+			changes: map[string]AuditChange{
+				"name":  {Before: "a"},
+				"email": {Before: "a@example.com"},
+			},
+		},
+		{
+			name:   "only the changed fields",
+			before: auditSubject{Name: "a", Email: "a@example.com", UpdatedAt: 1},
+			after:  auditSubject{Name: "b", Email: "a@example.com", UpdatedAt: 2},
+			changes: map[string]AuditChange{
+				"name": {Before: "a", After: "b"},
+			},
+		},
+		{
+			name:    "hidden fields and updatedAt are never recorded",
+			before:  auditSubject{Name: "a", Password: "old hash", UpdatedAt: 1},
+			after:   auditSubject{Name: "a", Password: "new hash", UpdatedAt: 2},
+			changes: nil,
+		},
+		{
+			name:   "maps",
+			before: map[string]interface{}{"required": false},
+			after:  map[string]interface{}{"required": true, "count": 1},
+			changes: map[string]AuditChange{
+				"required": {Before: false, After: true},
+				"count":    {After: float64(1)},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes, err := AuditDiff(tc.before, tc.after)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.changes, changes)
+		})
+	}
+}
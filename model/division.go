@@ -0,0 +1,27 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type Division struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"size:64;uniqueIndex;not null"`
+	Description string `json:"description" gorm:"size:255;not null;default:''"`
+
+	Users       []User       `json:"users,omitempty" gorm:"many2many:user_divisions"`
+	Permissions []Permission `json:"permissions,omitempty" gorm:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (d Division) GetID() uint {
+	return d.ID
+}
+
+// GetPrefixedNameID returns the casbin subject of the division
+func (d Division) GetPrefixedNameID() string {
+	return fmt.Sprintf("%s%d", DomPrefix, d.ID)
+}
@@ -8,6 +8,7 @@ type EntityOption struct {
 	SortBy         *filters.SortFilter
 	Op             string
 	IncludeDeleted bool
+	OnlyDeleted    bool
 	Offset         *int
 	Limit          *int
 }
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// UserIdentity links the user to its account at an external identity provider
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"index;not null"`
+	Issuer    string    `json:"issuer" gorm:"size:255;uniqueIndex:idx_user_identities_issuer_subject;not null"`
+	Subject   string    `json:"subject" gorm:"size:255;uniqueIndex:idx_user_identities_issuer_subject;not null"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GroupMapping grants the members of an identity provider group a division, and a role in it when set
+type GroupMapping struct {
+	Group      string
+	DivisionID uint
+	Role       string
+}
+
+// ParseGroupMappings parses the comma separated "group:divisionID" or "group:divisionID:role" mappings
+func ParseGroupMappings(s string) ([]GroupMapping, error) {
+	var mappings []GroupMapping
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		fields := strings.Split(item, ":")
+		if len(fields) < 2 || len(fields) > 3 || fields[0] == "" {
+			return nil, errors.Errorf("invalid group mapping %q", item)
+		}
+		divisionID, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil || divisionID == 0 {
+			return nil, errors.Errorf("invalid division ID of group mapping %q", item)
+		}
+
+		mapping := GroupMapping{Group: fields[0], DivisionID: uint(divisionID)}
+		if len(fields) == 3 {
+			mapping.Role = fields[2]
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
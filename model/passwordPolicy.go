@@ -0,0 +1,125 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// PasswordPolicy decides which passwords users can choose
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is the number of latest passwords, the current one included, that can not be reused, 0 to allow reuse
+	HistorySize int
+
+	blocklist map[string]struct{}
+}
+
+// LoadBlocklist reads the common passwords to reject, one per line, ignoring blank and # comment lines
+func (p *PasswordPolicy) LoadBlocklist(r io.Reader) error {
+	if p.blocklist == nil {
+		p.blocklist = make(map[string]struct{})
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "read password blocklist")
+	}
+	return nil
+}
+
+// Validate returns every rule the password of the user breaks
+func (p PasswordPolicy) Validate(password string, user User) *ValidationError {
+	reasons := []string{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("Password is too short (minimum %d characters)", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("Password is too long (maximum %d characters)", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		reasons = append(reasons, "Password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		reasons = append(reasons, "Password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		reasons = append(reasons, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		reasons = append(reasons, "Password must contain a symbol")
+	}
+
+	if containsPersonalInfo(password, user) {
+		reasons = append(reasons, "Password must not contain the email or name")
+	}
+
+	if _, ok := p.blocklist[strings.ToLower(password)]; ok {
+		reasons = append(reasons, "Password is too common")
+	}
+
+	if len(reasons) > 0 {
+		return &ValidationError{ItemName: "Password", Reasons: reasons}
+	}
+
+	return nil
+}
+
+// containsPersonalInfo reports whether the password contains the email local part
+// or a word of the name, ignoring case and parts shorter than 3 characters
+func containsPersonalInfo(password string, user User) bool {
+	password = strings.ToLower(password)
+
+	parts := strings.Fields(user.Name)
+	if local, _, found := strings.Cut(user.Email, "@"); found {
+		parts = append(parts, local)
+	}
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, strings.ToLower(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PasswordHistory is a previous password hash of the user, kept to reject its reuse
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"index;not null"`
+	Password  string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"createdAt"`
+}
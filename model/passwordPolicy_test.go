@@ -0,0 +1,142 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     8,
+		MaxLength:     16,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+	assert.NoError(t, policy.LoadBlocklist(strings.NewReader("# common\n\nPassw0rd!\n")))
+	user := User{Email: "alice.smith@example.com", Name: "Alice Smith"}
+
+	testCases := []struct {
+		name     string
+		password string
+		reasons  []string
+	}{
+		{
+			name:     "valid",
+			password: "Tr0ub4dor&3",
+		},
+		{
+			name:     "too short",
+			password: "Ab1!",
+			reasons:  []string{"Password is too short (minimum 8 characters)"},
+		},
+		{
+			name:     "too long",
+			password: "Tr0ub4dor&3Tr0ub4dor&3",
+			reasons:  []string{"Password is too long (maximum 16 characters)"},
+		},
+		{
+			name:     "multibyte characters count once",
+			password: "Pässwörd1!üüüüüü",
+		},
+		{
+			name:     "multibyte characters too short",
+			password: "Äö1!äöü",
+			reasons:  []string{"Password is too short (minimum 8 characters)"},
+		},
+		{
+			name:     "missing classes",
+			password: "abcdefgh",
+			reasons: []string{
+				"Password must contain an uppercase letter",
+				"Password must contain a digit",
+				"Password must contain a symbol",
+			},
+		},
+		{
+			name:     "missing lowercase",
+			password: "ABCDEFG1!",
+			reasons:  []string{"Password must contain a lowercase letter"},
+		},
+		{
+			name:     "name",
+			password: "xSMITH-2024y",
+			reasons:  []string{"Password must not contain the email or name"},
+		},
+		{
+			name:     "email local part",
+			password: "Alice.Smith#1",
+			reasons:  []string{"Password must not contain the email or name"},
+		},
+		{
+			name:     "blocklist ignores case",
+			password: "pASSW0RD!",
+			reasons:  []string{"Password is too common"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vErr := policy.Validate(tc.password, user)
+			if tc.reasons == nil {
+				assert.Nil(t, vErr)
+				return
+			}
+			if assert.NotNil(t, vErr) {
+				assert.Equal(t, tc.reasons, vErr.Reasons)
+			}
+		})
+	}
+}
+
+func TestContainsPersonalInfo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		password string
+		user     User
+		contains bool
+	}{
+		{
+			name:     "word of the name",
+			password: "ilovebobby",
+			user:     User{Name: "Bobby Tables"},
+			contains: true,
+		},
+		{
+			name:     "email local part ignoring case",
+			password: "xxJDOExx",
+			user:     User{Email: "jdoe@example.com"},
+			contains: true,
+		},
+		{
+			name:     "email domain is allowed",
+			password: "example-password",
+			user:     User{Email: "jdoe@example.com"},
+		},
+		{
+			name:     "parts shorter than 3 characters are ignored",
+			password: "al-jo-password",
+			user:     User{Name: "Al Jo", Email: "jo@example.com"},
+		},
+		{
+			name:     "multibyte part of 3 characters",
+			password: "我是王小明的密碼",
+			user:     User{Name: "王小明"},
+			contains: true,
+		},
+		{
+			name:     "empty user",
+			password: "anything",
+			user:     User{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.contains, containsPersonalInfo(tc.password, tc.user))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/a5932016/go-ddd-example/util/copy"
@@ -12,9 +13,26 @@ import (
 )
 
 const (
-	DomPrefix = "div:"
-	ObjPrefix = "obj:"
-	ActPrefix = "act:"
+	DomPrefix  = "div:"
+	ObjPrefix  = "obj:"
+	ActPrefix  = "act:"
+	UserPrefix = "user:"
+	RolePrefix = "role:"
+)
+
+// Casbin policy fields: p = sub, dom, obj, act
+const (
+	PolicySubIndex = iota
+	PolicyDomIndex
+	PolicyObjIndex
+	PolicyActIndex
+)
+
+// Casbin grouping fields: g = user, role, dom
+const (
+	GroupingUserIndex = iota
+	GroupingRoleIndex
+	GroupingDomIndex
 )
 
 const (
@@ -24,7 +42,8 @@ const (
 	PrefixedActionDelete = string(ActPrefix + ActionDelete)
 
 	// root resources
-	PrefixedResourceUser = string(ObjPrefix + ResourceUser)
+	PrefixedResourceUser     = string(ObjPrefix + ResourceUser)
+	PrefixedResourceDivision = string(ObjPrefix + ResourceDivision)
 
 	// admin resources
 )
@@ -36,7 +55,7 @@ type Permission struct {
 
 type ResourceAction struct {
 	Name        Action `json:"name" binding:"required"`
-	Status      bool   `json:"status" binding:"required"`
+	Status      bool   `json:"status"`
 	IsAvailable bool   `json:"isAvailable" binding:"-"`
 }
 
@@ -86,9 +105,40 @@ func (ph *PermissionsHandler) initAllAllowedPermissions() {
 		}
 
 		permission.Actions = permissionActions
+		permissions = append(permissions, permission)
+	}
+
+	ph.AllAllowedPermissions = sortPermissions(permissions)
+}
+
+// Catalog returns every resource action of the rules file, none of them enabled
+func (ph PermissionsHandler) Catalog() []Permission {
+	var permissions []Permission
+	for resourceObject, mResourceAction := range ph.stuffedPermissionMap {
+		permission := Permission{Name: resourceObject}
+		for _, permissionAction := range mResourceAction {
+			permissionAction.Status = false
+			permission.Actions = append(permission.Actions, permissionAction)
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return sortPermissions(permissions)
+}
+
+// sortPermissions keeps the matrix order stable for the frontend
+func sortPermissions(permissions []Permission) []Permission {
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+	for _, permission := range permissions {
+		sort.Slice(permission.Actions, func(i, j int) bool {
+			return permission.Actions[i].Name < permission.Actions[j].Name
+		})
 	}
 
-	ph.AllAllowedPermissions = permissions
+	return permissions
 }
 
 func (ph PermissionsHandler) CasbinPoliciesToPermissions(policies [][]string) (permissions []Permission, err error) {
@@ -100,9 +150,12 @@ func (ph PermissionsHandler) CasbinPoliciesToPermissions(policies [][]string) (p
 
 	// Enable new policy rules
 	for _, p := range policies {
-		obj := Resource(strings.TrimPrefix(p[1], ObjPrefix))
-		act := Action(strings.TrimPrefix(p[2], ActPrefix))
-		permissionAction := copiedPermissionMap[obj][act]
+		obj := Resource(strings.TrimPrefix(p[PolicyObjIndex], ObjPrefix))
+		act := Action(strings.TrimPrefix(p[PolicyActIndex], ActPrefix))
+		permissionAction, ok := copiedPermissionMap[obj][act]
+		if !ok { // Skip rules no longer defined in the rules file
+			continue
+		}
 		permissionAction.Status = true
 		copiedPermissionMap[obj][act] = permissionAction
 	}
@@ -117,15 +170,16 @@ func (ph PermissionsHandler) CasbinPoliciesToPermissions(policies [][]string) (p
 		permissions = append(permissions, permission)
 	}
 
-	return permissions, nil
+	return sortPermissions(permissions), nil
 }
 
-func (ph PermissionsHandler) PermissionsToCasbinPolicies(prefixedDivisionNameId string, permissions []Permission) [][]string {
+func (ph PermissionsHandler) PermissionsToCasbinPolicies(subject, prefixedDivisionNameId string, permissions []Permission) [][]string {
 	var rules [][]string
 	for _, perm := range permissions {
 		for _, permAction := range perm.Actions {
 			if permAction.Status && ph.stuffedPermissionMap[perm.Name][permAction.Name].IsAvailable { // Only add rules for allowed actions
 				rule := []string{
+					subject,
 					prefixedDivisionNameId,
 					perm.Name.Prefix(),
 					permAction.Name.Prefix(),
@@ -139,6 +193,22 @@ func (ph PermissionsHandler) PermissionsToCasbinPolicies(prefixedDivisionNameId
 	return rules
 }
 
+// IsAvailable tells whether the rules file defines the resource action as available
+func (ph PermissionsHandler) IsAvailable(resource Resource, action Action) bool {
+	return ph.stuffedPermissionMap[resource][action].IsAvailable
+}
+
+// GetPrefixedRole returns the casbin subject of the role
+func GetPrefixedRole(role string) string {
+	return fmt.Sprintf("%s%s", RolePrefix, role)
+}
+
+// RoleAssignment is a user's role in a division
+type RoleAssignment struct {
+	UserID uint   `json:"userId"`
+	Role   string `json:"role"`
+}
+
 type Resource string
 
 func (ro Resource) Prefix() string {
@@ -146,7 +216,9 @@ func (ro Resource) Prefix() string {
 }
 
 const (
-	ResourceUser Resource = "user"
+	ResourceUser         Resource = "user"
+	ResourceDivision     Resource = "division"
+	ResourceAnnouncement Resource = "announcement"
 )
 
 type Action string
@@ -164,9 +236,9 @@ const (
 
 func GetDefaultDivisionCasbinPolicies(prefixedDivisionNameID string) [][]string {
 	return [][]string{
-		// {prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionRead},
-		// {prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionCreate},
-		// {prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionUpdate},
-		// {prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionDelete},
+		// {prefixedDivisionNameID, prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionRead},
+		// {prefixedDivisionNameID, prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionCreate},
+		// {prefixedDivisionNameID, prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionUpdate},
+		// {prefixedDivisionNameID, prefixedDivisionNameID, PrefixedResourceUser, PrefixedActionDelete},
 	}
 }
@@ -1,8 +1,15 @@
 package model
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
@@ -13,7 +20,106 @@ type User struct {
 	Password string `json:"-" gorm:"not null"`
 	IsRoot   bool   `json:"isRoot" gorm:"default:false;not null"`
 
+	// Two-factor authentication
+	TOTPSecret    string             `json:"-" gorm:"column:totp_secret;size:64;not null;default:''"`
+	TOTPEnabled   bool               `json:"totpEnabled" gorm:"column:totp_enabled;default:false;not null"`
+	TOTPRequired  bool               `json:"totpRequired" gorm:"column:totp_required;default:false;not null"`
+	TOTPLastStep  int64              `json:"-" gorm:"column:totp_last_step;default:0;not null"` // rejects the reuse of a code
+	RecoveryCodes []UserRecoveryCode `json:"-"`
+	APIKeys       []APIKey           `json:"-"`
+	Identities    []UserIdentity     `json:"-"`
+	// Previous passwords, rejected by the password policy
+	PasswordHistories []PasswordHistory `json:"-"`
+
+	Divisions []Division `json:"divisions,omitempty" gorm:"many2many:user_divisions"`
+
 	CreatedAt time.Time      `json:"createdAt"`
 	UpdatedAt time.Time      `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+}
+
+func (u User) GetID() uint {
+	return u.ID
+}
+
+// GetPrefixedID returns the casbin subject of the user
+func (u User) GetPrefixedID() string {
+	return fmt.Sprintf("%s%d", UserPrefix, u.ID)
+}
+
+// PasswordResetToken is the record of a password reset approved by a root user,
+// or requested by the user themself when ApproverID is 0
+type PasswordResetToken struct {
+	UserID     uint      `json:"userId"`
+	ApproverID uint      `json:"approverId"`
+	ApproverIP string    `json:"approverIp"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// RefreshToken is the record of a refresh token of the jwt strategy.
+// Every token rotated from the same login shares its Family, revoked as a whole when a used token comes back.
+type RefreshToken struct {
+	UserID    uint      `json:"userId"`
+	Family    string    `json:"family"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Second factor steps of a pending login
+const (
+	TwoFactorVerify = "verify" // enter a code of the enabled authenticator
+	TwoFactorEnroll = "enroll" // required but not set up yet, set up the authenticator first
+)
+
+// ContextUser is the context key of the authenticated request user, set by the permission middleware
+const ContextUser = "requestUser"
+
+// LoginResult is an authenticated session, or a pre-auth token when the login waits for its second factor
+type LoginResult struct {
+	SessionID string
+	User      User
+
+	PreAuthToken string
+	TwoFactor    string
+
+	// Tokens of the jwt strategy, instead of the session
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // access token lifetime in seconds
+
+	// RecoveryCodes are returned once, when the login enrolled the user in two-factor authentication
+	RecoveryCodes []string
+
+	// Linked is set when the single sign-on callback linked the identity to the signed-in user, without a login
+	Linked bool
+}
+
+// UserRecoveryCode is a single-use two-factor recovery code, stored hashed
+type UserRecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"userId" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// GenerateRecoveryCodes returns n random codes formatted as "xxxxx-xxxxx"
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, "rand.Read")
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes the code ignoring its case, spaces and dashes
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
 }
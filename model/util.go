@@ -8,7 +8,6 @@ import (
 	"unicode"
 
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Generic type constraint
@@ -16,6 +15,12 @@ type HasID interface {
 	GetID() uint
 }
 
+// Versioned is an entity with a version column, bumped by every update to detect concurrent writes
+type Versioned interface {
+	GetVersion() uint
+	SetVersion(version uint)
+}
+
 // Function to extract IDs from a slice of structs
 func ExtractIDs[T HasID](data []T) []uint {
 	var ids []uint
@@ -41,19 +46,6 @@ func TrimAllSpace(s string) string {
 	return strings.Join(strings.Fields(s), "")
 }
 
-// User
-func HashPassword(password string) ([]byte, error) {
-	// Choose a suitable cost factor for bcrypt
-	cost := 12
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	return bytes, err
-}
-
-func VerifyPassword(password, hashedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil // true if the passwords match
-}
-
 type ValidationError struct {
 	ItemName string
 	Reasons  []string
@@ -82,26 +74,6 @@ func ValidateStringLength(itemName, value string, min, max int) *ValidationError
 	return nil
 }
 
-func ValidatePassword(password string) *ValidationError {
-	minLength := 8        // 8-12 characters (the absolute minimum for basic security)
-	maxLength := 64       // 64-128 characters (allows for passphrases, but some systems have limits)
-	reasons := []string{} // Start with an empty list of reasons
-
-	if len(password) < minLength {
-		reasons = append(reasons, fmt.Sprintf("Password is too short (minimum %d characters)", minLength))
-	}
-
-	if len(password) > maxLength {
-		reasons = append(reasons, fmt.Sprintf("Password is too long (maximum %d characters)", maxLength))
-	}
-
-	if len(reasons) > 0 {
-		return &ValidationError{ItemName: "Password", Reasons: reasons}
-	}
-
-	return nil // Password passed validation
-}
-
 // Image
 
 type ImageExtension int
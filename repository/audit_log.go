@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+)
+
+// NewAuditLog returns the entry of the action on the target by the request user,
+// with the IP and the ID of the request
+func NewAuditLog(c context.Context, action string, resource model.Resource, targetID interface{}) model.AuditLog {
+	entry := model.AuditLog{Action: action, Resource: string(resource)}
+	if targetID != nil {
+		entry.TargetID = fmt.Sprint(targetID)
+	}
+	if user, ok := c.Value(model.ContextUser).(model.User); ok {
+		entry.ActorID = user.ID
+	}
+	if requestID, ok := c.Value(mGin.ContextKeyRequestID).(string); ok {
+		entry.RequestID = requestID
+	}
+	if client, ok := c.(interface{ ClientIP() string }); ok {
+		entry.IP = client.ClientIP()
+	}
+	return entry
+}
+
+// WriteAuditLog appends the entry with the changes from before to after, either may be nil,
+// logging instead of failing the audited action when it can not be written
+func WriteAuditLog(c context.Context, dbRepo DBRepository, entry model.AuditLog, before, after interface{}) {
+	if before != nil || after != nil {
+		changes, err := model.AuditDiff(before, after)
+		if err != nil {
+			log.FromContext(c).WithError(err).Errorf("model.AuditDiff(%s)", entry.Action)
+		}
+		entry.Changes = changes
+	}
+
+	if err := dbRepo.CreateAuditLog(c, &entry); err != nil {
+		log.FromContext(c).WithError(err).WithField("auditLog", entry).Error("dbRepo.CreateAuditLog")
+	}
+}
@@ -2,69 +2,158 @@ package casbin
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"sync"
 
+	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/util/log"
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 func NewPERRepository(db *gorm.DB, configPath, tableName string) (*PERRepository, error) {
-	enforcer, err := newEnforcer(db, configPath, tableName)
+	enforcer, adapter, err := newEnforcer(db, configPath, tableName)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PERRepository{
 		enforcer:   enforcer,
+		adapter:    adapter,
 		lock:       new(sync.RWMutex),
 		configPath: configPath,
 		tableName:  tableName,
 	}, nil
 }
 
-func newEnforcer(db *gorm.DB, configPath, tableName string) (*casbin.CachedEnforcer, error) {
+func newEnforcer(db *gorm.DB, configPath, tableName string) (*casbin.CachedEnforcer, *gormadapter.Adapter, error) {
 	adapter, err := gormadapter.NewAdapterByDBUseTableName(db, "", tableName)
 	if err != nil {
-		return nil, errors.Wrap(err, "gormadapter.NewAdapterByDBUseTableName")
+		return nil, nil, errors.Wrap(err, "gormadapter.NewAdapterByDBUseTableName")
 	}
 
 	enforcer, err := casbin.NewCachedEnforcer(configPath, adapter) // will auto migrate
 	if err != nil {
-		return nil, errors.Wrap(err, "casbin.NewEnforcer")
+		return nil, nil, errors.Wrap(err, "casbin.NewEnforcer")
 	}
 
-	return enforcer, nil
+	// Grouping policies of domain "*" apply to every domain
+	enforcer.AddNamedDomainMatchingFunc("g", "keyMatch", util.KeyMatch)
+	if err := enforcer.LoadPolicy(); err != nil {
+		return nil, nil, errors.Wrap(err, "enforcer.LoadPolicy")
+	}
+
+	return enforcer, adapter, nil
 }
 
 // PERRepository PERRepository
 type PERRepository struct {
 	enforcer   *casbin.CachedEnforcer
+	adapter    *gormadapter.Adapter
+	watcher    persist.WatcherEx
 	withTx     bool
 	lock       *sync.RWMutex
 	configPath string
 	tableName  string
 }
 
-func (r *PERRepository) BeginWithTx(db *gorm.DB) (txPerRepo *PERRepository, closeTx func(c context.Context), err error) {
-	enforcer, err := newEnforcer(db, r.configPath, r.tableName)
+// SetWatcher syncs policy changes with the other instances through the watcher
+func (r *PERRepository) SetWatcher(watcher persist.WatcherEx) error {
+	if err := r.enforcer.SetWatcher(watcher); err != nil {
+		return errors.Wrap(err, "enforcer.SetWatcher")
+	}
+	r.watcher = watcher
+
+	return watcher.SetUpdateCallback(r.onPolicyUpdate)
+}
+
+// onPolicyUpdate applies a policy change made by another instance
+func (r *PERRepository) onPolicyUpdate(payload string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.applyPolicyMessage(payload); err != nil {
+		log.WithError(err).Warning("PERRepository: apply policy change failed, reload policy")
+		if err := r.enforcer.LoadPolicy(); err != nil {
+			log.WithError(err).Error("PERRepository: enforcer.LoadPolicy")
+		}
+		return
+	}
+
+	if err := r.enforcer.InvalidateCache(); err != nil {
+		log.WithError(err).Error("PERRepository: enforcer.InvalidateCache")
+	}
+}
+
+func (r *PERRepository) applyPolicyMessage(payload string) (err error) {
+	var msg policyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return errors.Wrap(err, "json.Unmarshal")
+	}
+
+	// The change is already persisted by the sender
+	r.enforcer.EnableAutoSave(false)
+	defer r.enforcer.EnableAutoSave(true)
+
+	switch msg.Op {
+	case policyOpAddPolicies:
+		_, err = r.enforcer.SelfAddPoliciesEx(msg.Sec, msg.PType, msg.Rules)
+	case policyOpRemovePolicies:
+		_, err = r.enforcer.SelfRemovePolicies(msg.Sec, msg.PType, msg.Rules)
+	case policyOpRemoveFilteredPolicy:
+		_, err = r.enforcer.SelfRemoveFilteredPolicy(msg.Sec, msg.PType, msg.FieldIndex, msg.FieldValues...)
+	default:
+		err = r.enforcer.LoadPolicy()
+	}
+
+	return err
+}
+
+// notifyReload asks the other instances to reload the whole policy
+func (r *PERRepository) notifyReload(c context.Context) {
+	if r.watcher == nil {
+		return
+	}
+	if err := r.watcher.Update(); err != nil {
+		log.FromContext(c).WithError(err).Error("watcher.Update")
+	}
+}
+
+// Close stops syncing policy changes
+func (r *PERRepository) Close() {
+	if r.watcher != nil {
+		r.watcher.Close()
+	}
+}
+
+// BeginWithTx returns the repository writing through the transaction db, holding the write lock until closeTx.
+// closeTx reloads the policy, and only after a commit asks the other instances to reload theirs.
+func (r *PERRepository) BeginWithTx(db *gorm.DB) (txPerRepo *PERRepository, closeTx func(c context.Context, committed bool), err error) {
+	enforcer, adapter, err := newEnforcer(db, r.configPath, r.tableName)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	closeTx = func(c context.Context) {
+	closeTx = func(c context.Context, committed bool) {
 		r.lock.Unlock()
-		if err := r.LoadPolicy(); err != nil {
+		if err := r.LoadPolicy(c); err != nil {
 			log.FromContext(c).WithError(err).Error("CloseTx")
 		}
+		if committed {
+			r.notifyReload(c)
+		}
 	}
 
 	r.lock.Lock()
 
 	return &PERRepository{
 		enforcer:   enforcer,
+		adapter:    adapter,
 		withTx:     true,
 		lock:       r.lock,
 		configPath: r.configPath,
@@ -72,7 +161,7 @@ func (r *PERRepository) BeginWithTx(db *gorm.DB) (txPerRepo *PERRepository, clos
 	}, closeTx, nil
 }
 
-func (r *PERRepository) Enforce(rvals ...interface{}) (bool, error) {
+func (r *PERRepository) Enforce(c context.Context, rvals ...interface{}) (bool, error) {
 	if !r.withTx {
 		r.lock.RLock()
 		defer r.lock.RUnlock()
@@ -81,7 +170,7 @@ func (r *PERRepository) Enforce(rvals ...interface{}) (bool, error) {
 	return r.enforcer.Enforce(rvals...)
 }
 
-func (r *PERRepository) LoadPolicy() error {
+func (r *PERRepository) LoadPolicy(c context.Context) error {
 	if !r.withTx {
 		r.lock.RLock()
 		defer r.lock.RUnlock()
@@ -90,33 +179,283 @@ func (r *PERRepository) LoadPolicy() error {
 	return r.enforcer.LoadPolicy()
 }
 
-func (r *PERRepository) GetPolicies(prefixedDivisionNameId string) ([][]string, error) {
+// GetPolicies returns the subject's own policies in the domain
+func (r *PERRepository) GetPolicies(c context.Context, subject, domain string) ([][]string, error) {
+	if !r.withTx {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+	}
+
+	return r.enforcer.GetPermissionsForUser(subject, domain)
+}
+
+// GetImplicitPermissions returns the user's policies in the domain, including the ones of its roles
+func (r *PERRepository) GetImplicitPermissions(c context.Context, user, domain string) ([][]string, error) {
+	if !r.withTx {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+	}
+
+	return r.enforcer.GetImplicitPermissionsForUser(user, domain)
+}
+
+func (r *PERRepository) GetRolesForUser(c context.Context, user, domain string) ([]string, error) {
+	if !r.withTx {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+	}
+
+	return r.enforcer.GetRolesForUser(user, domain)
+}
+
+// GetDomainsForUser returns the domains where the user has a role
+func (r *PERRepository) GetDomainsForUser(c context.Context, user string) ([]string, error) {
+	if !r.withTx {
+		r.lock.RLock()
+		defer r.lock.RUnlock()
+	}
+
+	return r.enforcer.GetDomainsForUser(user)
+}
+
+// GetRoleAssignments returns the grouping policies of the domain
+func (r *PERRepository) GetRoleAssignments(c context.Context, domain string) ([][]string, error) {
 	if !r.withTx {
 		r.lock.RLock()
 		defer r.lock.RUnlock()
 	}
 
-	return r.enforcer.GetPermissionsForUser(prefixedDivisionNameId)
+	return r.enforcer.GetFilteredGroupingPolicy(model.GroupingDomIndex, domain)
+}
+
+// saveWithContext saves a change with the request context through save, then applies it to the enforcer without saving it again.
+// The enforcer's own writes ignore the context, except in a transaction, whose queries are already bound to its context.
+// Every write of the repository goes through it.
+func (r *PERRepository) saveWithContext(save func(adapter *gormadapter.Adapter) error, apply func() error) error {
+	if !r.withTx {
+		if err := save(r.adapter); err != nil {
+			return err
+		}
+		r.enforcer.EnableAutoSave(false)
+		defer r.enforcer.EnableAutoSave(true)
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	return r.enforcer.InvalidateCache()
 }
 
-func (r *PERRepository) AddPoliciesEx(rules [][]string) error {
+func (r *PERRepository) AddRoleForUserInDomain(c context.Context, user, role, domain string) error {
 	if !r.withTx {
 		r.lock.Lock()
 		defer r.lock.Unlock()
 	}
 
-	_, err := r.enforcer.AddPoliciesEx(rules)
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		return adapter.AddPolicyCtx(c, "g", "g", []string{user, role, domain})
+	}, func() error {
+		_, err := r.enforcer.AddRoleForUserInDomain(user, role, domain)
+		return err
+	})
+}
 
-	return err
+func (r *PERRepository) DeleteRoleForUserInDomain(c context.Context, user, role, domain string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		return adapter.RemovePolicyCtx(c, "g", "g", []string{user, role, domain})
+	}, func() error {
+		_, err := r.enforcer.DeleteRoleForUserInDomain(user, role, domain)
+		return err
+	})
 }
 
-func (r *PERRepository) RemovePolicies(rules [][]string) error {
+// AddRoleInheritance lets role inherit the policies of parentRole in the domain, "*" for every domain
+func (r *PERRepository) AddRoleInheritance(c context.Context, role, parentRole, domain string) error {
 	if !r.withTx {
 		r.lock.Lock()
 		defer r.lock.Unlock()
 	}
 
-	_, err := r.enforcer.RemovePolicies(rules)
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		return adapter.AddPolicyCtx(c, "g", "g", []string{role, parentRole, domain})
+	}, func() error {
+		_, err := r.enforcer.AddGroupingPolicy(role, parentRole, domain)
+		return err
+	})
+}
 
-	return err
+func (r *PERRepository) DeleteRoleInheritance(c context.Context, role, parentRole, domain string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		return adapter.RemovePolicyCtx(c, "g", "g", []string{role, parentRole, domain})
+	}, func() error {
+		_, err := r.enforcer.RemoveGroupingPolicy(role, parentRole, domain)
+		return err
+	})
+}
+
+func (r *PERRepository) AddPoliciesEx(c context.Context, rules [][]string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	// Like the enforcer, skip the rules already there instead of failing on them
+	var added [][]string
+	for _, rule := range rules {
+		if hasRule(added, rule) {
+			continue
+		}
+		has, err := r.enforcer.HasPolicy(rule)
+		if err != nil {
+			return errors.Wrap(err, "enforcer.HasPolicy")
+		}
+		if !has {
+			added = append(added, rule)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		return addPoliciesCtx(c, adapter, added)
+	}, func() error {
+		_, err := r.enforcer.AddPoliciesEx(added)
+		return err
+	})
+}
+
+// addPoliciesCtx adds the policy rules with the context, the adapter has no batch for it
+func addPoliciesCtx(c context.Context, adapter *gormadapter.Adapter, rules [][]string) error {
+	for _, rule := range rules {
+		if err := adapter.AddPolicyCtx(c, "p", "p", rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasRule(rules [][]string, rule []string) bool {
+	for _, r := range rules {
+		if strings.Join(r, ",") == strings.Join(rule, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplacePolicies replaces the subject's policies in the domain with rules by adding the missing ones and removing the revoked ones
+func (r *PERRepository) ReplacePolicies(c context.Context, subject, domain string, rules [][]string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	current, err := r.enforcer.GetPermissionsForUser(subject, domain)
+	if err != nil {
+		return errors.Wrap(err, "enforcer.GetPermissionsForUser")
+	}
+
+	added, removed := diffRules(current, rules)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		if err := addPoliciesCtx(c, adapter, added); err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		return adapter.RemovePoliciesCtx(c, "p", "p", removed)
+	}, func() error {
+		if len(added) > 0 {
+			if _, err := r.enforcer.AddPoliciesEx(added); err != nil {
+				return errors.Wrap(err, "enforcer.AddPoliciesEx")
+			}
+		}
+		if len(removed) > 0 {
+			if _, err := r.enforcer.RemovePolicies(removed); err != nil {
+				return errors.Wrap(err, "enforcer.RemovePolicies")
+			}
+		}
+		return nil
+	})
+}
+
+// diffRules returns the rules only in newRules and the rules only in oldRules
+func diffRules(oldRules, newRules [][]string) (added, removed [][]string) {
+	oldSet := make(map[string]bool, len(oldRules))
+	for _, rule := range oldRules {
+		oldSet[strings.Join(rule, ",")] = true
+	}
+	newSet := make(map[string]bool, len(newRules))
+	for _, rule := range newRules {
+		key := strings.Join(rule, ",")
+		if !oldSet[key] && !newSet[key] {
+			added = append(added, rule)
+		}
+		newSet[key] = true
+	}
+	for _, rule := range oldRules {
+		if !newSet[strings.Join(rule, ",")] {
+			removed = append(removed, rule)
+		}
+	}
+
+	return added, removed
+}
+
+// DeleteDomain removes every policy and role assignment of the domain
+func (r *PERRepository) DeleteDomain(c context.Context, domain string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		if err := adapter.RemoveFilteredPolicyCtx(c, "p", "p", model.PolicyDomIndex, domain); err != nil {
+			return err
+		}
+		return adapter.RemoveFilteredPolicyCtx(c, "g", "g", model.GroupingDomIndex, domain)
+	}, func() error {
+		if _, err := r.enforcer.RemoveFilteredPolicy(model.PolicyDomIndex, domain); err != nil {
+			return err
+		}
+		_, err := r.enforcer.RemoveFilteredGroupingPolicy(model.GroupingDomIndex, domain)
+		return err
+	})
+}
+
+// DeleteSubject removes every policy and role assignment of the subject
+func (r *PERRepository) DeleteSubject(c context.Context, subject string) error {
+	if !r.withTx {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+	}
+
+	return r.saveWithContext(func(adapter *gormadapter.Adapter) error {
+		if err := adapter.RemoveFilteredPolicyCtx(c, "p", "p", model.PolicySubIndex, subject); err != nil {
+			return err
+		}
+		return adapter.RemoveFilteredPolicyCtx(c, "g", "g", model.GroupingUserIndex, subject)
+	}, func() error {
+		if _, err := r.enforcer.RemoveFilteredPolicy(model.PolicySubIndex, subject); err != nil {
+			return err
+		}
+		_, err := r.enforcer.RemoveFilteredGroupingPolicy(model.GroupingUserIndex, subject)
+		return err
+	})
 }
@@ -0,0 +1,136 @@
+package casbin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newTestDB returns a shared in-memory database, named after the test so tests do not see each other's policies
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return db
+}
+
+func newTestPERRepository(t *testing.T, db *gorm.DB) *PERRepository {
+	perRepo, err := NewPERRepository(db, "../../casbin.conf", "casbin_rules")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return perRepo
+}
+
+func TestPERRepositoryContext(t *testing.T) {
+	perRepo := newTestPERRepository(t, newTestDB(t))
+	ctx := context.Background()
+
+	assert.NoError(t, perRepo.AddRoleForUserInDomain(ctx, "usr:1", "role:admin", "div:1"))
+	roles, err := perRepo.GetRolesForUser(ctx, "usr:1", "div:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"role:admin"}, roles)
+
+	// A canceled request does not write
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, perRepo.AddRoleForUserInDomain(canceled, "usr:2", "role:admin", "div:1"))
+	assert.Error(t, perRepo.DeleteRoleForUserInDomain(canceled, "usr:1", "role:admin", "div:1"))
+
+	assert.NoError(t, perRepo.LoadPolicy(ctx))
+	roles, err = perRepo.GetRolesForUser(ctx, "usr:2", "div:1")
+	assert.NoError(t, err)
+	assert.Empty(t, roles)
+	roles, err = perRepo.GetRolesForUser(ctx, "usr:1", "div:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"role:admin"}, roles)
+
+	// The policy writes take the context too
+	read := []string{"role:admin", "div:1", "obj:user", "act:read"}
+	update := []string{"role:admin", "div:1", "obj:user", "act:update"}
+	request := []interface{}{"usr:1", "div:1", "obj:user", "act:update"}
+	assert.NoError(t, perRepo.AddPoliciesEx(ctx, [][]string{read, read}))
+	assert.NoError(t, perRepo.AddPoliciesEx(ctx, [][]string{read}), "existing rules are skipped")
+	assert.Error(t, perRepo.AddPoliciesEx(canceled, [][]string{update}))
+	assert.Error(t, perRepo.ReplacePolicies(canceled, "role:admin", "div:1", [][]string{update}))
+	assert.Error(t, perRepo.DeleteDomain(canceled, "div:1"))
+
+	assert.NoError(t, perRepo.LoadPolicy(ctx))
+	ok, err := perRepo.Enforce(ctx, request...)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	roles, err = perRepo.GetRolesForUser(ctx, "usr:1", "div:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"role:admin"}, roles)
+
+	assert.NoError(t, perRepo.ReplacePolicies(ctx, "role:admin", "div:1", [][]string{update}))
+	assert.NoError(t, perRepo.LoadPolicy(ctx))
+	ok, err = perRepo.Enforce(ctx, request...)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = perRepo.Enforce(ctx, "usr:1", "div:1", "obj:user", "act:read")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, perRepo.DeleteDomain(ctx, "div:1"))
+	assert.NoError(t, perRepo.LoadPolicy(ctx))
+	roles, err = perRepo.GetRolesForUser(ctx, "usr:1", "div:1")
+	assert.NoError(t, err)
+	assert.Empty(t, roles)
+}
+
+func TestPERRepositoryWatcher(t *testing.T) {
+	db := newTestDB(t)
+	bus := NewLocalWatcherBus()
+	ctx := context.Background()
+
+	first, second := newTestPERRepository(t, db), newTestPERRepository(t, db)
+	for _, perRepo := range []*PERRepository{first, second} {
+		watcher, err := bus.NewWatcher()
+		assert.NoError(t, err)
+		assert.NoError(t, perRepo.SetWatcher(watcher))
+	}
+	observer, err := bus.NewWatcher()
+	assert.NoError(t, err)
+	var published int
+	assert.NoError(t, observer.SetUpdateCallback(func(string) { published++ }))
+
+	request := []interface{}{"usr:1", "div:1", "obj:user", "act:read"}
+	rule := []string{"role:admin", "div:1", "obj:user", "act:read"}
+
+	// A rolled back transaction is not published
+	tx := db.Begin()
+	txPer, closeTx, err := first.BeginWithTx(tx)
+	assert.NoError(t, err)
+	assert.NoError(t, txPer.AddPoliciesEx(ctx, [][]string{rule}))
+	assert.NoError(t, tx.Rollback().Error)
+	closeTx(ctx, false)
+	assert.Zero(t, published)
+
+	// A committed transaction is reloaded by the other instances
+	tx = db.Begin()
+	txPer, closeTx, err = first.BeginWithTx(tx)
+	assert.NoError(t, err)
+	assert.NoError(t, txPer.AddPoliciesEx(ctx, [][]string{rule}))
+	assert.NoError(t, tx.Commit().Error)
+	closeTx(ctx, true)
+	assert.Equal(t, 1, published)
+
+	// An incremental change is applied by the other instances
+	assert.NoError(t, first.AddRoleForUserInDomain(ctx, "usr:1", "role:admin", "div:1"))
+	assert.Equal(t, 2, published)
+
+	ok, err := second.Enforce(ctx, request...)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, first.DeleteRoleForUserInDomain(ctx, "usr:1", "role:admin", "div:1"))
+	ok, err = second.Enforce(ctx, request...)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
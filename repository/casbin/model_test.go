@@ -0,0 +1,55 @@
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainRoleModel(t *testing.T) {
+	enforcer, err := casbin.NewEnforcer("../../casbin.conf")
+	assert.NoError(t, err)
+	enforcer.AddNamedDomainMatchingFunc("g", "keyMatch", util.KeyMatch)
+
+	_, err = enforcer.AddPolicies([][]string{
+		{"role:viewer", "div:1", "obj:user", "act:read"},
+		{"role:editor", "div:1", "obj:user", "act:update"},
+		{"role:admin", "*", "obj:*", "act:*"},
+	})
+	assert.NoError(t, err)
+	_, err = enforcer.AddGroupingPolicies([][]string{
+		{"role:editor", "role:viewer", "div:1"},
+		{"user:1", "role:editor", "div:1"},
+		{"user:1", "role:viewer", "div:2"},
+		{"user:2", "role:admin", "*"},
+	})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		sub, dom, obj, act string
+		allowed            bool
+	}{
+		{"user:1", "div:1", "obj:user", "act:update", true},
+		// Inherited from role:viewer
+		{"user:1", "div:1", "obj:user", "act:read", true},
+		// role:viewer has no policy in div:2
+		{"user:1", "div:2", "obj:user", "act:read", false},
+		{"user:1", "div:1", "obj:user", "act:delete", false},
+		// Wildcard domain and resources
+		{"user:2", "div:3", "obj:division", "act:delete", true},
+	}
+	for _, c := range cases {
+		ok, err := enforcer.Enforce(c.sub, c.dom, c.obj, c.act)
+		assert.NoError(t, err)
+		assert.Equal(t, c.allowed, ok, "%v", c)
+	}
+
+	permissions, err := enforcer.GetImplicitPermissionsForUser("user:1", "div:1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{
+		{"role:editor", "div:1", "obj:user", "act:update"},
+		{"role:viewer", "div:1", "obj:user", "act:read"},
+	}, permissions)
+}
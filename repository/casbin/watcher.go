@@ -0,0 +1,200 @@
+package casbin
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const DefaultWatcherChannel = "casbin_policy_update"
+
+var (
+	_ persist.WatcherEx = (*PolicyWatcher)(nil)
+)
+
+type policyOp string
+
+const (
+	policyOpReload               policyOp = "reload"
+	policyOpAddPolicies          policyOp = "addPolicies"
+	policyOpRemovePolicies       policyOp = "removePolicies"
+	policyOpRemoveFilteredPolicy policyOp = "removeFilteredPolicy"
+)
+
+// policyMessage is the payload broadcast to the other instances
+type policyMessage struct {
+	InstanceID  string     `json:"instanceId"`
+	Op          policyOp   `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	PType       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	FieldIndex  int        `json:"fieldIndex,omitempty"`
+	FieldValues []string   `json:"fieldValues,omitempty"`
+}
+
+// watcherTransport broadcasts payloads between instances
+type watcherTransport interface {
+	Publish(payload string) error
+	Subscribe(handle func(payload string)) (closeFn func() error, err error)
+}
+
+// PolicyWatcher notifies the other instances about policy changes and
+// passes their changes to the update callback
+type PolicyWatcher struct {
+	instanceID string
+	transport  watcherTransport
+	closeFn    func() error
+
+	lock     sync.RWMutex
+	callback func(string)
+}
+
+// NewRedisWatcher new policy watcher with redis pub/sub as transport
+func NewRedisWatcher(memRepo repository.MemRepository, channel string) (*PolicyWatcher, error) {
+	return newPolicyWatcher(redisTransport{
+		memRepo: memRepo,
+		channel: channel,
+	})
+}
+
+func newPolicyWatcher(transport watcherTransport) (*PolicyWatcher, error) {
+	w := &PolicyWatcher{
+		instanceID: uuid.New().String(),
+		transport:  transport,
+	}
+
+	closeFn, err := transport.Subscribe(w.receive)
+	if err != nil {
+		return nil, errors.Wrap(err, "transport.Subscribe")
+	}
+	w.closeFn = closeFn
+
+	return w, nil
+}
+
+func (w *PolicyWatcher) receive(payload string) {
+	var msg policyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.WithError(err).Error("PolicyWatcher: json.Unmarshal")
+		return
+	}
+
+	// Skip the changes made by this instance
+	if msg.InstanceID == w.instanceID {
+		return
+	}
+
+	w.lock.RLock()
+	callback := w.callback
+	w.lock.RUnlock()
+
+	if callback != nil {
+		callback(payload)
+	}
+}
+
+func (w *PolicyWatcher) publish(msg policyMessage) error {
+	msg.InstanceID = w.instanceID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+
+	return w.transport.Publish(string(payload))
+}
+
+// SetUpdateCallback sets the function called with the payload of the other instances' changes
+func (w *PolicyWatcher) SetUpdateCallback(callback func(string)) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.callback = callback
+	return nil
+}
+
+// Update asks the other instances to reload the whole policy
+func (w *PolicyWatcher) Update() error {
+	return w.publish(policyMessage{Op: policyOpReload})
+}
+
+func (w *PolicyWatcher) Close() {
+	if w.closeFn == nil {
+		return
+	}
+	if err := w.closeFn(); err != nil {
+		log.WithError(err).Error("PolicyWatcher: close")
+	}
+}
+
+func (w *PolicyWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForAddPolicies(sec, ptype, params)
+}
+
+func (w *PolicyWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.UpdateForRemovePolicies(sec, ptype, params)
+}
+
+func (w *PolicyWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(policyMessage{
+		Op:          policyOpRemoveFilteredPolicy,
+		Sec:         sec,
+		PType:       ptype,
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	})
+}
+
+func (w *PolicyWatcher) UpdateForSavePolicy(model model.Model) error {
+	return w.Update()
+}
+
+func (w *PolicyWatcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{
+		Op:    policyOpAddPolicies,
+		Sec:   sec,
+		PType: ptype,
+		Rules: rules,
+	})
+}
+
+func (w *PolicyWatcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{
+		Op:    policyOpRemovePolicies,
+		Sec:   sec,
+		PType: ptype,
+		Rules: rules,
+	})
+}
+
+type redisTransport struct {
+	memRepo repository.MemRepository
+	channel string
+}
+
+// The casbin watcher callbacks carry no request context, so the transport uses the background one
+func (t redisTransport) Publish(payload string) error {
+	_, err := t.memRepo.Publish(context.Background(), t.channel, payload)
+	return err
+}
+
+func (t redisTransport) Subscribe(handle func(payload string)) (func() error, error) {
+	messages, closeFn, err := t.memRepo.Subscribe(context.Background(), t.channel)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for payload := range messages {
+			handle(payload)
+		}
+	}()
+
+	return closeFn, nil
+}
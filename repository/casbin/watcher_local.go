@@ -0,0 +1,63 @@
+package casbin
+
+import (
+	"sync"
+)
+
+// LocalWatcherBus broadcasts policy changes between the watchers of one process, for tests
+type LocalWatcherBus struct {
+	lock     sync.RWMutex
+	nextID   int
+	handlers map[int]func(payload string)
+}
+
+func NewLocalWatcherBus() *LocalWatcherBus {
+	return &LocalWatcherBus{
+		handlers: make(map[int]func(payload string)),
+	}
+}
+
+// NewWatcher new policy watcher attached to the bus
+func (b *LocalWatcherBus) NewWatcher() (*PolicyWatcher, error) {
+	return newPolicyWatcher(localTransport{bus: b})
+}
+
+func (b *LocalWatcherBus) publish(payload string) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	// Deliver synchronously so tests observe the change right after the write
+	for _, handle := range b.handlers {
+		handle(payload)
+	}
+}
+
+func (b *LocalWatcherBus) subscribe(handle func(payload string)) func() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handle
+
+	return func() error {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		delete(b.handlers, id)
+		return nil
+	}
+}
+
+type localTransport struct {
+	bus *LocalWatcherBus
+}
+
+func (t localTransport) Publish(payload string) error {
+	t.bus.publish(payload)
+	return nil
+}
+
+func (t localTransport) Subscribe(handle func(payload string)) (func() error, error) {
+	return t.bus.subscribe(handle), nil
+}
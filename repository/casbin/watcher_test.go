@@ -0,0 +1,58 @@
+package casbin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalWatcher(t *testing.T) {
+	bus := NewLocalWatcherBus()
+
+	sender, err := bus.NewWatcher()
+	assert.NoError(t, err)
+	receiver, err := bus.NewWatcher()
+	assert.NoError(t, err)
+
+	var senderPayloads, receiverPayloads []string
+	assert.NoError(t, sender.SetUpdateCallback(func(payload string) {
+		senderPayloads = append(senderPayloads, payload)
+	}))
+	assert.NoError(t, receiver.SetUpdateCallback(func(payload string) {
+		receiverPayloads = append(receiverPayloads, payload)
+	}))
+
+	rule := []string{"div:1", "div:1", "obj:user", "act:read"}
+	assert.NoError(t, sender.UpdateForAddPolicies("p", "p", rule))
+
+	// The sender skips its own change
+	assert.Empty(t, senderPayloads)
+	if assert.Len(t, receiverPayloads, 1) {
+		var msg policyMessage
+		assert.NoError(t, json.Unmarshal([]byte(receiverPayloads[0]), &msg))
+		assert.Equal(t, policyOpAddPolicies, msg.Op)
+		assert.Equal(t, [][]string{rule}, msg.Rules)
+	}
+
+	// A closed watcher stops receiving
+	receiver.Close()
+	assert.NoError(t, sender.Update())
+	assert.Len(t, receiverPayloads, 1)
+}
+
+func TestDiffRules(t *testing.T) {
+	oldRules := [][]string{
+		{"div:1", "div:1", "obj:user", "act:read"},
+		{"div:1", "div:1", "obj:user", "act:update"},
+	}
+	newRules := [][]string{
+		{"div:1", "div:1", "obj:user", "act:read"},
+		{"div:1", "div:1", "obj:user", "act:create"},
+		{"div:1", "div:1", "obj:user", "act:create"},
+	}
+
+	added, removed := diffRules(oldRules, newRules)
+	assert.Equal(t, [][]string{{"div:1", "div:1", "obj:user", "act:create"}}, added)
+	assert.Equal(t, [][]string{{"div:1", "div:1", "obj:user", "act:update"}}, removed)
+}
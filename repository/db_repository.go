@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/singleton/entity"
 
@@ -11,6 +14,9 @@ import (
 type DBRepository interface {
 	RDBMS
 	User
+	Division
+	APIKey
+	AuditLog
 }
 
 type RDBMS interface {
@@ -19,14 +25,53 @@ type RDBMS interface {
 	Debug()
 	EntityCtrl() *entity.EntityHandler
 
-	// transaction
-	Begin() DBRepository
-	Commit() error
-	Rollback() error
+	// Transaction runs fn in a transaction bound to ctx, committed when fn returns nil and rolled back otherwise.
+	// Calling it on the repository of a transaction nests a savepoint.
+	Transaction(ctx context.Context, fn func(tx DBRepository) error) error
 }
 
 type User interface {
-	GetUser(id uint) (user model.User, err error)
-	GetUserByAccount(email string) (user model.User, err error)
-	UpdateUserPassword(id uint, password string) error
+	GetUser(ctx context.Context, id uint) (user model.User, err error)
+	GetUserByAccount(ctx context.Context, email string) (user model.User, err error)
+	ListUsers(ctx context.Context, opt model.EntityOption) (users []model.User, total int64, err error)
+	CreateUser(ctx context.Context, user *model.User) error
+	UpdateUser(ctx context.Context, user model.User) error
+	UpdateUserPassword(ctx context.Context, id uint, password string, keepHistory int) error
+	UpgradeUserPassword(ctx context.Context, id uint, previous, password string) error
+	ListPasswordHistory(ctx context.Context, id uint, limit int) (histories []model.PasswordHistory, err error)
+	UpdateUserTwoFactor(ctx context.Context, user model.User) error
+	ClaimUserTOTPStep(ctx context.Context, id uint, step int64) (bool, error)
+	ReplaceUserRecoveryCodes(ctx context.Context, id uint, codeHashes []string) error
+	UseUserRecoveryCode(ctx context.Context, id uint, codeHash string) (bool, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (user model.User, err error)
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	AddUserDivisions(ctx context.Context, id uint, divisionIDs []uint) error
+	RemoveUserDivisions(ctx context.Context, id uint, divisionIDs []uint) error
+	DeleteUser(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) error
+	PurgeUser(ctx context.Context, id uint) error
+}
+
+type APIKey interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (key model.APIKey, err error)
+	ListAPIKeys(ctx context.Context, userID uint) (keys []model.APIKey, err error)
+	DeleteAPIKey(ctx context.Context, userID, id uint) error
+	TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+// AuditLog is append-only, entries are removed only by the retention
+type AuditLog interface {
+	CreateAuditLog(ctx context.Context, entry *model.AuditLog) error
+	ListAuditLogs(ctx context.Context, filter model.AuditLogFilter, opt model.EntityOption) (entries []model.AuditLog, total int64, err error)
+	PurgeAuditLogs(ctx context.Context, before time.Time) (purged int64, err error)
+}
+
+type Division interface {
+	GetDivision(ctx context.Context, id uint) (division model.Division, err error)
+	ListDivisions(ctx context.Context, opt model.EntityOption) (divisions []model.Division, total int64, err error)
+	CreateDivision(ctx context.Context, division *model.Division) error
+	UpdateDivision(ctx context.Context, division model.Division) error
+	DeleteDivision(ctx context.Context, id uint) error
+	ReplaceDivisionUsers(ctx context.Context, id uint, userIDs []uint) error
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-redsync/redsync/v4"
@@ -16,14 +17,28 @@ type redisI interface {
 	NewMutex(name string, options ...redsync.Option) *redsync.Mutex
 	GetAPILimiter() limiter.Store
 
-	Set(key string, value interface{}, expiration time.Duration) (string, error)
-	Del(keys ...string) (int64, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) (string, error)
+	MGet(ctx context.Context, keys ...string) ([]interface{}, error)
+	GetDel(ctx context.Context, key string) (string, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	IncrEx(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Del(ctx context.Context, keys ...string) (int64, error)
 
-	HSet(key string, values ...interface{}) (int64, error)
-	HSetEx(key, field string, value interface{}, expiration time.Duration) error
-	HGet(key string, field string) (string, error)
-	HDel(key string, fields ...string) (int64, error)
+	HSet(ctx context.Context, key string, values ...interface{}) (int64, error)
+	HSetEx(ctx context.Context, key, field string, value interface{}, expiration time.Duration) error
+	HGet(ctx context.Context, key string, field string) (string, error)
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
 
-	Expire(key string, expiration time.Duration) (bool, error)
-	Exists(keys ...string) (int64, error)
+	ZAdd(ctx context.Context, key string, score float64, member string) (int64, error)
+	ZRange(ctx context.Context, key string) ([]string, error)
+	ZRem(ctx context.Context, key string, members ...string) (int64, error)
+
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	Exists(ctx context.Context, keys ...string) (int64, error)
+
+	Publish(ctx context.Context, channel string, message interface{}) (int64, error)
+	Subscribe(ctx context.Context, channel string) (messages <-chan string, closeFn func() error, err error)
 }
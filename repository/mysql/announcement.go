@@ -0,0 +1,15 @@
+package mysql
+
+import (
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGorm"
+	"gorm.io/gorm"
+)
+
+// AnnouncementListQuery filters the announcements listed by the entity routes
+func AnnouncementListQuery(db *gorm.DB, filter model.AnnouncementFilter) *gorm.DB {
+	mDB := mGorm.New(db)
+	mDB = mDB.WhereWithStringFilter("title", filter.Title, "")
+	mDB = mDB.WhereWithTimestampFilter("created_at", filter.CreatedAt, "")
+	return mDB.DB
+}
@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"gorm.io/gorm"
+)
+
+func (s *DBRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return s.db.WithContext(ctx).Create(key).Error
+}
+
+func (s *DBRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (key model.APIKey, err error) {
+	err = s.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+	return
+}
+
+func (s *DBRepository) ListAPIKeys(ctx context.Context, userID uint) (keys []model.APIKey, err error) {
+	err = s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	return
+}
+
+// DeleteAPIKey deletes the key of the user, gorm.ErrRecordNotFound when the user has no such key
+func (s *DBRepository) DeleteAPIKey(ctx context.Context, userID, id uint) error {
+	result := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.APIKey{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *DBRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	return s.db.WithContext(ctx).Model(&model.APIKey{ID: id}).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,41 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGorm"
+	"github.com/pkg/errors"
+)
+
+func (s *DBRepository) CreateAuditLog(ctx context.Context, entry *model.AuditLog) error {
+	return s.db.WithContext(ctx).Create(entry).Error
+}
+
+func (s *DBRepository) ListAuditLogs(ctx context.Context, filter model.AuditLogFilter, opt model.EntityOption) (entries []model.AuditLog, total int64, err error) {
+	mDB := mGorm.New(s.db.WithContext(ctx).Model(&model.AuditLog{}))
+	mDB = mDB.WhereWithNumberFilter("actor_id", filter.ActorID, opt.Op)
+	mDB = mDB.WhereWithStringFilter("action", filter.Action, opt.Op)
+	mDB = mDB.WhereWithStringFilter("resource", filter.Resource, opt.Op)
+	mDB = mDB.WhereWithStringFilter("target_id", filter.TargetID, opt.Op)
+	mDB = mDB.WhereWithStringFilter("request_id", filter.RequestID, opt.Op)
+	mDB = mDB.WhereWithTimestampFilter("created_at", filter.CreatedAt, opt.Op)
+
+	if err = mDB.DB.Count(&total).Error; err != nil {
+		err = errors.Wrap(err, "Failed to count audit logs")
+		return
+	}
+
+	if err = GetEntityDB(mDB.DB, opt).Find(&entries).Error; err != nil {
+		err = errors.Wrap(err, "Failed to select audit logs")
+		return
+	}
+	return
+}
+
+// PurgeAuditLogs deletes the entries created before the time
+func (s *DBRepository) PurgeAuditLogs(ctx context.Context, before time.Time) (purged int64, err error) {
+	result := s.db.WithContext(ctx).Where("created_at < ?", before).Delete(&model.AuditLog{})
+	return result.RowsAffected, result.Error
+}
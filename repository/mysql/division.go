@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func (s *DBRepository) GetDivision(ctx context.Context, id uint) (division model.Division, err error) {
+	err = s.db.WithContext(ctx).Preload("Users").Where("id = ?", id).First(&division).Error
+	return
+}
+
+func (s *DBRepository) ListDivisions(ctx context.Context, opt model.EntityOption) (divisions []model.Division, total int64, err error) {
+	db := s.db.WithContext(ctx).Model(&model.Division{})
+	if opt.Keyword != nil {
+		keyword := fmt.Sprint("%", *opt.Keyword, "%")
+		db = db.Where("name LIKE ?", keyword)
+		opt.Keyword = nil
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		err = errors.Wrap(err, "Failed to count divisions")
+		return
+	}
+
+	if err = GetEntityDB(db, opt).Find(&divisions).Error; err != nil {
+		err = errors.Wrap(err, "Failed to select divisions")
+		return
+	}
+	return
+}
+
+func (s *DBRepository) CreateDivision(ctx context.Context, division *model.Division) error {
+	return s.db.WithContext(ctx).Omit("Users").Create(division).Error
+}
+
+func (s *DBRepository) UpdateDivision(ctx context.Context, division model.Division) error {
+	return s.db.WithContext(ctx).Model(&model.Division{ID: division.ID}).
+		Select("name", "description").
+		Updates(&division).Error
+}
+
+func (s *DBRepository) DeleteDivision(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Select("Users").Delete(&model.Division{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *DBRepository) ReplaceDivisionUsers(ctx context.Context, id uint, userIDs []uint) error {
+	association := s.db.WithContext(ctx).Model(&model.Division{ID: id}).Association("Users")
+	if len(userIDs) == 0 {
+		return association.Clear()
+	}
+
+	var users []model.User
+	if err := s.db.WithContext(ctx).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return errors.Wrap(err, "Failed to select users")
+	}
+	if len(users) != len(uniqueIDs(userIDs)) {
+		return gorm.ErrRecordNotFound
+	}
+
+	return association.Replace(users)
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
@@ -19,8 +19,8 @@ func GetEntityDB(db *gorm.DB, opt model.EntityOption) *gorm.DB {
 	mDB = mDB.OrderWithFilter(opt.SortBy)
 
 	if opt.Offset != nil && opt.Limit != nil {
-		db = db.Limit(*opt.Limit)
-		db = db.Offset(*opt.Offset)
+		mDB.DB = mDB.DB.Limit(*opt.Limit)
+		mDB.DB = mDB.DB.Offset(*opt.Offset)
 	}
 
 	return mDB.DB
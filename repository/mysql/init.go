@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"time"
 
 	"github.com/a5932016/go-ddd-example/repository"
@@ -60,21 +61,15 @@ func (s *DBRepository) EntityCtrl() *entity.EntityHandler {
 	return s.entityCtrl
 }
 
-// Begin begin a transaction
-func (s *DBRepository) Begin() repository.DBRepository {
-	return &DBRepository{
-		db: s.db.Begin(),
-	}
-}
-
-// Commit commit a transaction
-func (s *DBRepository) Commit() error {
-	return s.db.Commit().Error
-}
-
-// Rollback rollback a transaction
-func (s *DBRepository) Rollback() error {
-	return s.db.Rollback().Error
+// Transaction runs fn in a transaction, with the entity controller of the transaction
+func (s *DBRepository) Transaction(ctx context.Context, fn func(tx repository.DBRepository) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := &DBRepository{db: tx}
+		if s.entityCtrl != nil {
+			txRepo.entityCtrl = s.entityCtrl.Begin(tx)
+		}
+		return fn(txRepo)
+	})
 }
 
 // Debug Debug log
@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/singleton/entity"
+)
+
+type testAnnouncementDTO struct {
+	Title string
+}
+
+func (d testAnnouncementDTO) ToEntity() model.Announcement {
+	return model.Announcement{Title: d.Title}
+}
+
+// newTestEntityRepository returns a repository serving the announcements through its entity controller
+func newTestEntityRepository(t *testing.T) *DBRepository {
+	repo := newTestDBRepository(t, &model.Announcement{})
+	repo.entityCtrl = entity.NewEntityHandler(repo.db, []entity.Registration{
+		entity.Register(entity.Definition[model.Announcement, model.AnnouncementFilter, testAnnouncementDTO]{
+			Name:      "announcement",
+			Resource:  model.ResourceAnnouncement,
+			ListQuery: AnnouncementListQuery,
+		}),
+	})
+	return repo
+}
+
+// announcementTitles returns the titles of the announcements, read through the repository
+func announcementTitles(t *testing.T, repo repository.DBRepository) []string {
+	var announcements []model.Announcement
+	assert.NoError(t, repo.EntityCtrl().Entity(model.Announcement{}).List(&announcements, &model.AnnouncementFilter{}))
+	titles := []string{}
+	for _, announcement := range announcements {
+		titles = append(titles, announcement.Title)
+	}
+	return titles
+}
+
+func createAnnouncement(tx repository.DBRepository, title string) error {
+	return tx.EntityCtrl().Entity(model.Announcement{}).Create(&model.Announcement{Title: title})
+}
+
+func TestTransaction(t *testing.T) {
+	repo := newTestEntityRepository(t)
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	// The entity controller of fn writes in the transaction
+	err := repo.Transaction(ctx, func(tx repository.DBRepository) error {
+		if !assert.NotNil(t, tx.EntityCtrl()) {
+			return errFailed
+		}
+		assert.NotSame(t, repo.EntityCtrl(), tx.EntityCtrl())
+		assert.NoError(t, createAnnouncement(tx, "rolled back"))
+		assert.Equal(t, []string{"rolled back"}, announcementTitles(t, tx))
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Empty(t, announcementTitles(t, repo))
+
+	// A failed nested transaction rolls back to its savepoint only
+	err = repo.Transaction(ctx, func(tx repository.DBRepository) error {
+		assert.NoError(t, createAnnouncement(tx, "outer"))
+		nestedErr := tx.Transaction(ctx, func(nested repository.DBRepository) error {
+			assert.NoError(t, createAnnouncement(nested, "nested"))
+			return errFailed
+		})
+		assert.ErrorIs(t, nestedErr, errFailed)
+		assert.Equal(t, []string{"outer"}, announcementTitles(t, tx))
+
+		return tx.Transaction(ctx, func(nested repository.DBRepository) error {
+			return createAnnouncement(nested, "committed nested")
+		})
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"outer", "committed nested"}, announcementTitles(t, repo))
+}
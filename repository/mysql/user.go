@@ -1,20 +1,26 @@
 package mysql
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/util/mGorm"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
-func (s *DBRepository) GetUser(id uint) (user model.User, err error) {
-	mDB := mGorm.New(s.db.Model(&model.User{}))
-	mDB.DB = mDB.DB.Where("id = ?", id)
-	err = mDB.DB.Find(&user).Error
+func (s *DBRepository) GetUser(ctx context.Context, id uint) (user model.User, err error) {
+	mDB := mGorm.New(s.db.WithContext(ctx).Model(&model.User{}))
+	mDB.DB = mDB.DB.Preload("Divisions").Where("id = ?", id)
+	err = mDB.DB.First(&user).Error
 	return
 }
 
-func (s *DBRepository) GetUserByAccount(email string) (user model.User, err error) {
-	if err = s.db.
+func (s *DBRepository) GetUserByAccount(ctx context.Context, email string) (user model.User, err error) {
+	if err = s.db.WithContext(ctx).
+		Preload("Divisions").
 		Where("email = ?", email).
 		First(&user).Error; err != nil {
 		err = errors.Wrap(err, "Failed to select user")
@@ -23,6 +29,216 @@ func (s *DBRepository) GetUserByAccount(email string) (user model.User, err erro
 	return
 }
 
-func (s *DBRepository) UpdateUserPassword(id uint, password string) error {
-	return s.db.Model(&model.User{}).Where("id = ?", id).Update("password", password).Error
+func (s *DBRepository) ListUsers(ctx context.Context, opt model.EntityOption) (users []model.User, total int64, err error) {
+	db := s.db.WithContext(ctx).Model(&model.User{})
+	if opt.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	// Keyword matches both name and email
+	if opt.Keyword != nil {
+		keyword := fmt.Sprint("%", *opt.Keyword, "%")
+		db = db.Where("name LIKE ? OR email LIKE ?", keyword, keyword)
+		opt.Keyword = nil
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		err = errors.Wrap(err, "Failed to count users")
+		return
+	}
+
+	if err = GetEntityDB(db, opt).Find(&users).Error; err != nil {
+		err = errors.Wrap(err, "Failed to select users")
+		return
+	}
+	return
+}
+
+func (s *DBRepository) CreateUser(ctx context.Context, user *model.User) error {
+	return s.db.WithContext(ctx).Omit("Divisions", "RecoveryCodes", "APIKeys", "Identities", "PasswordHistories").Create(user).Error
+}
+
+func (s *DBRepository) UpdateUser(ctx context.Context, user model.User) error {
+	return s.db.WithContext(ctx).Model(&model.User{ID: user.ID}).
+		Select("email", "name", "is_root").
+		Updates(&user).Error
+}
+
+// UpdateUserPassword replaces the password, keeping the replaced one and the keepHistory-1 before it in the history
+func (s *DBRepository) UpdateUserPassword(ctx context.Context, id uint, password string, keepHistory int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous []string
+		if err := tx.Model(&model.User{}).Where("id = ?", id).Pluck("password", &previous).Error; err != nil {
+			return errors.Wrap(err, "Failed to select password")
+		}
+		if len(previous) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&model.User{}).Where("id = ?", id).Update("password", password).Error; err != nil {
+			return errors.Wrap(err, "Failed to update password")
+		}
+
+		if keepHistory <= 0 {
+			return tx.Where("user_id = ?", id).Delete(&model.PasswordHistory{}).Error
+		}
+		if err := tx.Create(&model.PasswordHistory{UserID: id, Password: previous[0]}).Error; err != nil {
+			return errors.Wrap(err, "Failed to create password history")
+		}
+
+		// Drop the entries older than the kept ones
+		var oldestDropped []uint
+		if err := tx.Model(&model.PasswordHistory{}).
+			Where("user_id = ?", id).
+			Order("id DESC").
+			Offset(keepHistory).
+			Limit(1).
+			Pluck("id", &oldestDropped).Error; err != nil {
+			return errors.Wrap(err, "Failed to select password history")
+		}
+		if len(oldestDropped) == 0 {
+			return nil
+		}
+		return tx.Where("user_id = ? AND id <= ?", id, oldestDropped[0]).Delete(&model.PasswordHistory{}).Error
+	})
+}
+
+// UpgradeUserPassword replaces the hash of the same password, unless the password changed since previous was read
+func (s *DBRepository) UpgradeUserPassword(ctx context.Context, id uint, previous, password string) error {
+	return s.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND password = ?", id, previous).
+		Update("password", password).Error
+}
+
+// ListPasswordHistory returns the latest previous passwords of the user, newest first
+func (s *DBRepository) ListPasswordHistory(ctx context.Context, id uint, limit int) (histories []model.PasswordHistory, err error) {
+	err = s.db.WithContext(ctx).Where("user_id = ?", id).Order("id DESC").Limit(limit).Find(&histories).Error
+	return
+}
+
+func (s *DBRepository) UpdateUserTwoFactor(ctx context.Context, user model.User) error {
+	return s.db.WithContext(ctx).Model(&model.User{ID: user.ID}).
+		Select("totp_secret", "totp_enabled", "totp_required", "totp_last_step").
+		Updates(&user).Error
+}
+
+// ClaimUserTOTPStep records the step of a verified code, false when the step was already used
+func (s *DBRepository) ClaimUserTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *DBRepository) ReplaceUserRecoveryCodes(ctx context.Context, id uint, codeHashes []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+			return errors.Wrap(err, "Failed to delete recovery codes")
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+
+		codes := make([]model.UserRecoveryCode, 0, len(codeHashes))
+		for _, codeHash := range codeHashes {
+			codes = append(codes, model.UserRecoveryCode{UserID: id, CodeHash: codeHash})
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return errors.Wrap(err, "Failed to create recovery codes")
+		}
+		return nil
+	})
+}
+
+// UseUserRecoveryCode marks the unused code as used, false when there is no such code
+func (s *DBRepository) UseUserRecoveryCode(ctx context.Context, id uint, codeHash string) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", id, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *DBRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (user model.User, err error) {
+	if err = s.db.WithContext(ctx).
+		Preload("Divisions").
+		Where("id = (?)", s.db.WithContext(ctx).Model(&model.UserIdentity{}).
+			Select("user_id").
+			Where("issuer = ? AND subject = ?", issuer, subject)).
+		First(&user).Error; err != nil {
+		err = errors.Wrap(err, "Failed to select user")
+		return
+	}
+	return
+}
+
+func (s *DBRepository) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	return s.db.WithContext(ctx).Create(identity).Error
+}
+
+func (s *DBRepository) AddUserDivisions(ctx context.Context, id uint, divisionIDs []uint) error {
+	if len(divisionIDs) == 0 {
+		return nil
+	}
+
+	var divisions []model.Division
+	if err := s.db.WithContext(ctx).Where("id IN ?", divisionIDs).Find(&divisions).Error; err != nil {
+		return errors.Wrap(err, "Failed to select divisions")
+	}
+	if len(divisions) != len(uniqueIDs(divisionIDs)) {
+		return gorm.ErrRecordNotFound
+	}
+
+	return s.db.WithContext(ctx).Model(&model.User{ID: id}).Association("Divisions").Append(divisions)
+}
+
+func (s *DBRepository) RemoveUserDivisions(ctx context.Context, id uint, divisionIDs []uint) error {
+	if len(divisionIDs) == 0 {
+		return nil
+	}
+	divisions := make([]model.Division, 0, len(divisionIDs))
+	for _, divisionID := range divisionIDs {
+		divisions = append(divisions, model.Division{ID: divisionID})
+	}
+	return s.db.WithContext(ctx).Model(&model.User{ID: id}).Association("Divisions").Delete(divisions)
+}
+
+func (s *DBRepository) DeleteUser(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Where("id = ?", id).Delete(&model.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *DBRepository) RestoreUser(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *DBRepository) PurgeUser(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Unscoped().Select("Divisions", "RecoveryCodes", "APIKeys", "Identities", "PasswordHistories").Delete(&model.User{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
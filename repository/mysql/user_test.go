@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/model"
+)
+
+func newTestDBRepository(t *testing.T, models ...interface{}) *DBRepository {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, db.AutoMigrate(models...)) {
+		t.FailNow()
+	}
+	return NewDBRepository(db, nil)
+}
+
+func TestUpdateUserPasswordKeepsHistory(t *testing.T) {
+	repo := newTestDBRepository(t, &model.User{}, &model.PasswordHistory{})
+	ctx := context.Background()
+
+	user := model.User{Email: "user@example.com", Name: "user", Password: "hash0"}
+	assert.NoError(t, repo.db.Create(&user).Error)
+
+	// A history size of 3 keeps the current password and the 2 before it
+	const keepHistory = 2
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, repo.UpdateUserPassword(ctx, user.ID, fmt.Sprintf("hash%d", i), keepHistory))
+	}
+
+	var current model.User
+	assert.NoError(t, repo.db.First(&current, user.ID).Error)
+	assert.Equal(t, "hash5", current.Password)
+
+	histories, err := repo.ListPasswordHistory(ctx, user.ID, 10)
+	assert.NoError(t, err)
+	var passwords []string
+	for _, history := range histories {
+		passwords = append(passwords, history.Password)
+	}
+	assert.Equal(t, []string{"hash4", "hash3"}, passwords)
+
+	// Without a history nothing is kept
+	assert.NoError(t, repo.UpdateUserPassword(ctx, user.ID, "hash6", 0))
+	histories, err = repo.ListPasswordHistory(ctx, user.ID, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, histories)
+
+	assert.ErrorIs(t, repo.UpdateUserPassword(ctx, user.ID+1, "hash", keepHistory), gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// NewFileNotifier appends the messages to the file, or logs them when path is empty. For local development and tests only,
+// which the configuration enforces for the logging.
+func NewFileNotifier(fs afero.Fs, path string) *FileNotifier {
+	return &FileNotifier{
+		fs:   fs,
+		path: path,
+	}
+}
+
+type FileNotifier struct {
+	lock sync.Mutex
+	fs   afero.Fs
+	path string
+}
+
+func (n *FileNotifier) Notify(c context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	if n.path == "" {
+		log.FromContext(c).WithFields(logrus.Fields{"to": msg.To, "subject": msg.Subject}).Info(msg.Body)
+		return nil
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	f, err := n.fs.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "fs.OpenFile")
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n---\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body); err != nil {
+		return errors.Wrap(err, "fmt.Fprintf")
+	}
+	return nil
+}
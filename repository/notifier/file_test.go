@@ -0,0 +1,26 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileNotifier(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	n := NewFileNotifier(fs, "/tmp/mail.log")
+
+	assert.NoError(t, n.Notify(context.Background(), Message{To: "a@example.com", Subject: "first", Body: "hello"}))
+	assert.NoError(t, n.Notify(context.Background(), Message{To: "b@example.com", Subject: "second", Body: "world"}))
+
+	content, err := afero.ReadFile(fs, "/tmp/mail.log")
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: a@example.com\nSubject: first\n\nhello\n---\n")
+	assert.Contains(t, string(content), "To: b@example.com\nSubject: second\n\nworld\n---\n")
+
+	// Header injection
+	assert.Error(t, n.Notify(context.Background(), Message{To: "a@example.com\r\nBcc: c@example.com", Subject: "x"}))
+	assert.Error(t, n.Notify(context.Background(), Message{Subject: "x"}))
+}
@@ -0,0 +1,31 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Message is a notification to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Notify(c context.Context, msg Message) error
+}
+
+// validate rejects header injection through the recipient or the subject
+func (msg Message) validate() error {
+	if msg.To == "" {
+		return errors.New("empty recipient")
+	}
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("line break in message header")
+	}
+	return nil
+}
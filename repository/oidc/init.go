@@ -0,0 +1,85 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Claims are the ID token claims used to sign in
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified *bool    `json:"email_verified"`
+	Name          string   `json:"name"`
+
+	// Groups are read from the configured groups claim
+	Groups []string `json:"-"`
+}
+
+// Valid checks the expiration, the issuer, the audience and the nonce are checked by the provider
+func (c Claims) Valid(now time.Time) error {
+	if now.Unix() >= c.ExpiresAt {
+		return jwt.ErrExpiredToken
+	}
+	return nil
+}
+
+// audience is a single audience or a list of them, RFC 7519 section 4.1.3
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPKCE returns a code verifier and its S256 challenge, RFC 7636
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// RandomString returns 32 random bytes encoded for URLs, used as state, nonce and code verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
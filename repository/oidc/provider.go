@@ -0,0 +1,299 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/pkg/errors"
+)
+
+// maxResponseSize bounds the documents read from the identity provider
+const maxResponseSize = 1 << 20
+
+// jwksRefreshInterval bounds how often the provider keys are fetched again for an unknown key ID,
+// so tokens of made-up key IDs can not flood the provider
+const jwksRefreshInterval = time.Minute
+
+// NewProvider is the client of the identity provider at issuerURL, discovered on first use
+func NewProvider(issuerURL, clientID, clientSecret, redirectURL string, scopes []string, groupsClaim string, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{
+		issuerURL:    strings.TrimSuffix(issuerURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       scopes,
+		groupsClaim:  groupsClaim,
+		client:       client,
+	}
+}
+
+// Provider runs the authorization code flow with PKCE against an OpenID Connect identity provider
+type Provider struct {
+	issuerURL    string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	groupsClaim  string
+	client       *http.Client
+
+	lock          sync.Mutex
+	discovery     *discovery
+	keys          map[string]*jwt.Signer // verifiers by key ID
+	keysFetchedAt time.Time
+	keysRefresh   chan struct{} // closed once the running fetch of the keys is done, nil without one
+}
+
+// discovery is the provider metadata, OpenID Connect Discovery 1.0 section 3
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// AuthCodeURL returns the URL the user is redirected to for signing in
+func (p *Provider) AuthCodeURL(c context.Context, state, nonce, codeChallenge string) (string, error) {
+	d, err := p.getDiscovery(c)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the claims of the verified ID token
+func (p *Provider) Exchange(c context.Context, code, codeVerifier, nonce string) (Claims, error) {
+	d, err := p.getDiscovery(c)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("client_id", p.clientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, errors.Wrap(err, "http.NewRequest")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &token); err != nil {
+		return Claims{}, errors.WithMessage(err, "token endpoint")
+	}
+	if token.IDToken == "" {
+		return Claims{}, errors.New("token endpoint returned no ID token")
+	}
+
+	return p.VerifyIDToken(c, token.IDToken, nonce)
+}
+
+// VerifyIDToken verifies the signature of the ID token against the provider keys, and its issuer, audience and nonce
+func (p *Provider) VerifyIDToken(c context.Context, rawIDToken, nonce string) (Claims, error) {
+	d, err := p.getDiscovery(c)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	alg, kid, err := jwt.ParseHeader(rawIDToken)
+	if err != nil || alg != jwt.RS256 {
+		return Claims{}, ErrInvalidIDToken
+	}
+	verifier, err := p.getKey(c, d, kid)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := verifier.Parse(rawIDToken, &claims); err != nil {
+		return Claims{}, errors.Wrap(ErrInvalidIDToken, err.Error())
+	}
+	if claims.Issuer != d.Issuer || !claims.Audience.contains(p.clientID) || claims.Subject == "" {
+		return Claims{}, ErrInvalidIDToken
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	if p.groupsClaim != "" {
+		if claims.Groups, err = readGroups(rawIDToken, p.groupsClaim); err != nil {
+			return Claims{}, err
+		}
+	}
+
+	return claims, nil
+}
+
+// readGroups reads the groups claim of a verified token, whose name differs between providers
+func readGroups(rawIDToken, groupsClaim string) ([]string, error) {
+	parts := strings.Split(rawIDToken, ".")
+	payload, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	value, ok := raw[groupsClaim]
+	if !ok {
+		return nil, nil
+	}
+	var groups []string
+	if err := json.Unmarshal(value, &groups); err != nil {
+		return nil, errors.Wrapf(ErrInvalidIDToken, "claim %s is not a list of strings", groupsClaim)
+	}
+	return groups, nil
+}
+
+func (p *Provider) getDiscovery(c context.Context) (*discovery, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(c, http.MethodGet, p.issuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "http.NewRequest")
+	}
+	var d discovery
+	if err := p.do(req, &d); err != nil {
+		return nil, errors.WithMessage(err, "discovery")
+	}
+	// The issuer of the metadata must be the configured one, OpenID Connect Discovery 1.0 section 4.3
+	if strings.TrimSuffix(d.Issuer, "/") != p.issuerURL {
+		return nil, errors.Errorf("discovery issuer %s does not match %s", d.Issuer, p.issuerURL)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// getKey returns the verifier of the key ID, fetching the provider keys again for an unknown one after a rotation,
+// at most once per jwksRefreshInterval. The keys are fetched outside the lock, which concurrent callers wait for.
+func (p *Provider) getKey(c context.Context, d *discovery, kid string) (*jwt.Signer, error) {
+	for {
+		p.lock.Lock()
+		if verifier, ok := p.keys[kid]; ok {
+			p.lock.Unlock()
+			return verifier, nil
+		}
+
+		if refresh := p.keysRefresh; refresh != nil {
+			p.lock.Unlock()
+			select {
+			case <-refresh:
+				continue
+			case <-c.Done():
+				return nil, c.Err()
+			}
+		}
+
+		if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+			p.lock.Unlock()
+			return nil, errors.Wrapf(ErrInvalidIDToken, "unknown key %s", kid)
+		}
+		refresh := make(chan struct{})
+		p.keysRefresh = refresh
+		p.keysFetchedAt = time.Now()
+		p.lock.Unlock()
+
+		keys, err := p.fetchKeys(c, d)
+
+		p.lock.Lock()
+		if err == nil {
+			p.keys = keys
+		}
+		p.keysRefresh = nil
+		close(refresh)
+		p.lock.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchKeys returns the verifiers of the provider's RSA signing keys by key ID
+func (p *Provider) fetchKeys(c context.Context, d *discovery) (map[string]*jwt.Signer, error) {
+	req, err := http.NewRequestWithContext(c, http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "http.NewRequest")
+	}
+	var set jwt.JWKSet
+	if err := p.do(req, &set); err != nil {
+		return nil, errors.WithMessage(err, "jwks")
+	}
+
+	keys := make(map[string]*jwt.Signer)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = jwt.NewRS256Verifier(publicKey, key.Kid)
+	}
+	return keys, nil
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "client.Do")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "io.ReadAll")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errors.Wrap(err, "json.Unmarshal")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockServer is a local OpenID Connect provider issuing ID tokens for the codes it hands out
+type mockServer struct {
+	*httptest.Server
+	signer *jwt.Signer
+	claims map[string]interface{}
+	// codes maps an authorization code to its code challenge and nonce
+	codes map[string][2]string
+	// jwksFetches counts the requests for the keys
+	jwksFetches int32
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	m := &mockServer{
+		signer: jwt.NewRS256Signer(privateKey, "mock-key"),
+		codes:  make(map[string][2]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&m.jwksFetches, 1)
+		json.NewEncoder(w).Encode(m.signer.JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		code, ok := m.codes[r.PostForm.Get("code")]
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code[0] {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		claims := map[string]interface{}{
+			"iss":   m.URL,
+			"sub":   "subject-1",
+			"aud":   []string{"client-1"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": code[1],
+			"email": "user@example.com",
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		idToken, err := m.signer.Sign(claims)
+		assert.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+// authorize stands for the user signing in at the authorization URL
+func (m *mockServer) authorize(t *testing.T, authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "client-1", query.Get("client_id"))
+
+	code, err = RandomString()
+	assert.NoError(t, err)
+	m.codes[code] = [2]string{query.Get("code_challenge"), query.Get("nonce")}
+	return code, query.Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	server := newMockServer(t)
+	server.claims = map[string]interface{}{"groups": []string{"engineering"}}
+	provider := NewProvider(server.URL, "client-1", "secret", "http://localhost/callback", []string{"openid", "email"}, "groups", nil)
+	c := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	assert.NoError(t, err)
+	authURL, err := provider.AuthCodeURL(c, "state-1", "nonce-1", challenge)
+	assert.NoError(t, err)
+
+	code, state := server.authorize(t, authURL)
+	assert.Equal(t, "state-1", state)
+
+	claims, err := provider.Exchange(c, code, verifier, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Email)
+	assert.Equal(t, []string{"engineering"}, claims.Groups)
+
+	// The nonce of another login is rejected
+	code, _ = server.authorize(t, authURL)
+	_, err = provider.Exchange(c, code, verifier, "nonce-2")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	// The code can not be redeemed without its verifier
+	code, _ = server.authorize(t, authURL)
+	_, err = provider.Exchange(c, code, "wrong-verifier", "nonce-1")
+	assert.Error(t, err)
+
+	// A token for another client is rejected
+	server.claims["aud"] = "client-2"
+	code, _ = server.authorize(t, authURL)
+	_, err = provider.Exchange(c, code, verifier, "nonce-1")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestVerifyIDTokenRejectsForeignKey(t *testing.T) {
+	server := newMockServer(t)
+	provider := NewProvider(server.URL, "client-1", "", "http://localhost/callback", []string{"openid"}, "", nil)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	forged, err := jwt.NewRS256Signer(privateKey, "mock-key").Sign(map[string]interface{}{
+		"iss":   server.URL,
+		"sub":   "subject-1",
+		"aud":   "client-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	})
+	assert.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), forged, "nonce-1")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestUnknownKeyRefreshIsRateLimited(t *testing.T) {
+	server := newMockServer(t)
+	provider := NewProvider(server.URL, "client-1", "", "http://localhost/callback", []string{"openid"}, "", nil)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	claims := map[string]interface{}{
+		"iss":   server.URL,
+		"sub":   "subject-1",
+		"aud":   "client-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	}
+
+	// Concurrent tokens of made-up key IDs share one fetch of the keys
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			forged, err := jwt.NewRS256Signer(privateKey, "made-up-"+string(rune('a'+i))).Sign(claims)
+			assert.NoError(t, err)
+			_, err = provider.VerifyIDToken(context.Background(), forged, "nonce-1")
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.jwksFetches))
+
+	// The known key is still served from the fetched keys
+	valid, err := server.signer.Sign(claims)
+	assert.NoError(t, err)
+	_, err = provider.VerifyIDToken(context.Background(), valid, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.jwksFetches))
+
+	// Another refresh is allowed once the interval passed
+	provider.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+	forged, err := jwt.NewRS256Signer(privateKey, "made-up-z").Sign(claims)
+	assert.NoError(t, err)
+	_, err = provider.VerifyIDToken(context.Background(), forged, "nonce-1")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.jwksFetches))
+}
@@ -26,7 +26,6 @@ func NewMemRepository(redisClient *redis.Client) (*MemRepository, error) {
 		client:     redisClient,
 		redsync:    redsync.New(goredis.NewPool(redisClient)),
 		apiLimiter: &limiterStore,
-		ctx:        context.Background(),
 	}, nil
 }
 
@@ -36,26 +35,56 @@ type MemRepository struct {
 	redsync     *redsync.Redsync
 	apiLimiter  *limiter.Store
 	scriptSHA1s sync.Map
-	ctx         context.Context
 }
 
 func (m *MemRepository) GetAPILimiter() limiter.Store {
 	return *m.apiLimiter
 }
 
-func (m *MemRepository) Set(key string, value interface{}, expiration time.Duration) (string, error) {
-	return m.client.Set(m.ctx, key, value, expiration).Result()
+func (m *MemRepository) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) (string, error) {
+	return m.client.Set(ctx, key, value, expiration).Result()
 }
 
-func (m *MemRepository) Del(keys ...string) (int64, error) {
-	return m.client.Del(m.ctx, keys...).Result()
+// MGet returns the values of the keys, nil for the missing ones
+func (m *MemRepository) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	return m.client.MGet(ctx, keys...).Result()
 }
 
-func (m *MemRepository) HSet(key string, values ...interface{}) (int64, error) {
-	return m.client.HSet(m.ctx, key, values...).Result()
+// GetDel returns the value and deletes the key atomically, empty for a missing key
+func (m *MemRepository) GetDel(ctx context.Context, key string) (string, error) {
+	value, err := m.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (m *MemRepository) Incr(ctx context.Context, key string) (int64, error) {
+	return m.client.Incr(ctx, key).Result()
+}
+
+// IncrEx increments the counter, starting its expiration with the first increment
+func (m *MemRepository) IncrEx(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	cmd, err := m.execScript(ctx, _LuaCMDIncrEx, []string{key}, formatSec(expiration))
+	if err != nil {
+		return 0, err
+	}
+	return cmd.Int64()
+}
+
+func (m *MemRepository) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return m.client.TTL(ctx, key).Result()
+}
+
+func (m *MemRepository) Del(ctx context.Context, keys ...string) (int64, error) {
+	return m.client.Del(ctx, keys...).Result()
+}
+
+func (m *MemRepository) HSet(ctx context.Context, key string, values ...interface{}) (int64, error) {
+	return m.client.HSet(ctx, key, values...).Result()
 }
 
-func (m *MemRepository) HSetEx(key, field string, value interface{}, expiration time.Duration) error {
+func (m *MemRepository) HSetEx(ctx context.Context, key, field string, value interface{}, expiration time.Duration) error {
 	script := redis.NewScript(`
         local key = KEYS[1]
         local field = ARGV[1]
@@ -70,24 +99,73 @@ func (m *MemRepository) HSetEx(key, field string, value interface{}, expiration
           return 'Error: Could not set field'
         end
     `)
-	_, err := script.Run(m.ctx, m.client, []string{key}, field, value, formatSec(expiration)).Result()
+	_, err := script.Run(ctx, m.client, []string{key}, field, value, formatSec(expiration)).Result()
 	return err
 }
 
-func (m *MemRepository) HGet(key string, field string) (string, error) {
-	return m.client.HGet(m.ctx, key, field).Result()
+func (m *MemRepository) HGet(ctx context.Context, key string, field string) (string, error) {
+	return m.client.HGet(ctx, key, field).Result()
+}
+
+func (m *MemRepository) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	return m.client.HIncrBy(ctx, key, field, incr).Result()
 }
 
-func (m *MemRepository) HDel(key string, fields ...string) (int64, error) {
-	return m.client.HDel(m.ctx, key, fields...).Result()
+func (m *MemRepository) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return m.client.HDel(ctx, key, fields...).Result()
 }
 
-func (m *MemRepository) Expire(key string, expiration time.Duration) (bool, error) {
-	return m.client.Expire(m.ctx, key, expiration).Result()
+func (m *MemRepository) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return m.client.HGetAll(ctx, key).Result()
 }
 
-func (m *MemRepository) Exists(keys ...string) (int64, error) {
-	return m.client.Exists(m.ctx, keys...).Result()
+func (m *MemRepository) ZAdd(ctx context.Context, key string, score float64, member string) (int64, error) {
+	return m.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Result()
+}
+
+// ZRange returns every member of the sorted set, lowest score first
+func (m *MemRepository) ZRange(ctx context.Context, key string) ([]string, error) {
+	return m.client.ZRange(ctx, key, 0, -1).Result()
+}
+
+func (m *MemRepository) ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		args[i] = member
+	}
+	return m.client.ZRem(ctx, key, args...).Result()
+}
+
+func (m *MemRepository) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return m.client.Expire(ctx, key, expiration).Result()
+}
+
+func (m *MemRepository) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return m.client.Exists(ctx, keys...).Result()
+}
+
+func (m *MemRepository) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return m.client.Publish(ctx, channel, message).Result()
+}
+
+// Subscribe subscribes the channel and forwards the payloads until closeFn is called
+func (m *MemRepository) Subscribe(ctx context.Context, channel string) (<-chan string, func() error, error) {
+	pubsub := m.client.Subscribe(ctx, channel)
+	// Wait for confirmation that subscription is created
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, errors.Wrap(err, "pubsub.Receive")
+	}
+
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for msg := range pubsub.Channel() {
+			messages <- msg.Payload
+		}
+	}()
+
+	return messages, pubsub.Close, nil
 }
 
 func formatSec(dur time.Duration) int64 {
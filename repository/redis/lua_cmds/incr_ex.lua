@@ -0,0 +1,6 @@
+-- Increments the counter KEYS[1], starting its expiration of ARGV[1] seconds with the first increment
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('EXPIRE', KEYS[1], tonumber(ARGV[1]))
+end
+return count
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -9,27 +10,37 @@ import (
 
 const (
 	_LuaCMDSetManyHash = "set_many_hash"
+	_LuaCMDIncrEx      = "incr_ex"
 )
 
-func (m *MemRepository) execScript(cmd string, keys []string, args ...interface{}) (*redis.Cmd, error) {
-	sha, err := m.getScriptSHA(cmd)
+func (m *MemRepository) execScript(ctx context.Context, cmd string, keys []string, args ...interface{}) (*redis.Cmd, error) {
+	sha, err := m.getScriptSHA(ctx, cmd)
 	if err != nil {
 		return nil, err
 	}
 
-	return m.client.EvalSha(m.ctx, sha, keys, args...), nil
+	result := m.client.EvalSha(ctx, sha, keys, args...)
+	// The server forgets the scripts when it restarts, load it again
+	if redis.HasErrorPrefix(result.Err(), "NOSCRIPT") {
+		if sha, err = m.loadScript(ctx, cmd); err != nil {
+			return nil, err
+		}
+		result = m.client.EvalSha(ctx, sha, keys, args...)
+	}
+
+	return result, nil
 }
 
-func (m *MemRepository) getScriptSHA(cmd string) (sha string, err error) {
+func (m *MemRepository) getScriptSHA(ctx context.Context, cmd string) (sha string, err error) {
 	val, ok := m.scriptSHA1s.Load(cmd)
 	if !ok {
-		return m.loadScript(cmd)
+		return m.loadScript(ctx, cmd)
 	}
 
 	return val.(string), nil
 }
 
-func (m *MemRepository) loadScript(cmd string) (sha string, err error) {
+func (m *MemRepository) loadScript(ctx context.Context, cmd string) (sha string, err error) {
 	filePath := fmt.Sprintf("repository/redis/lua_cmds/%s.lua", cmd)
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -37,13 +48,13 @@ func (m *MemRepository) loadScript(cmd string) (sha string, err error) {
 	}
 
 	script := string(data)
-	result := m.client.ScriptLoad(m.ctx, script)
+	result := m.client.ScriptLoad(ctx, script)
 	if err := result.Err(); err != nil {
 		return "", err
 	}
 
 	sha = result.Val()
-	m.scriptSHA1s.Store(_LuaCMDSetManyHash, sha)
+	m.scriptSHA1s.Store(cmd, sha)
 
 	return
 }
@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/a5932016/go-ddd-example/repository"
 	"github.com/a5932016/go-ddd-example/repository/casbin"
+	"github.com/a5932016/go-ddd-example/singleton/entity"
 	"github.com/a5932016/go-ddd-example/singleton/entityUsecase"
 	"github.com/a5932016/go-ddd-example/singleton/session"
 	"github.com/a5932016/go-ddd-example/usecase"
@@ -12,6 +13,7 @@ import (
 type Handler struct {
 	handler        usecase.Handler
 	entityHandler  entityUsecase.EntityUseCase
+	entities       []entity.Registration
 	memRepo        repository.MemRepository
 	perRepo        *casbin.PERRepository
 	sessionManager *session.Manager
@@ -21,6 +23,7 @@ type Handler struct {
 func NewRouter(
 	handler usecase.Handler,
 	entityHandler entityUsecase.EntityUseCase,
+	entities []entity.Registration,
 	memRepo repository.MemRepository,
 	perRepo *casbin.PERRepository,
 	sessionManager *session.Manager,
@@ -28,6 +31,7 @@ func NewRouter(
 	return Handler{
 		handler:        handler,
 		entityHandler:  entityHandler,
+		entities:       entities,
 		memRepo:        memRepo,
 		perRepo:        perRepo,
 		sessionManager: sessionManager,
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/gin-gonic/gin"
+)
+
+func (rH Handler) listAPIKeysHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	keys, err := rH.handler.ListAPIKeys(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListAPIKeys")
+		return
+	}
+
+	ctx.WithData(keys).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) createAPIKeyHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body viewModel.CreateAPIKey
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := model.ValidateStringLength("Name", model.NormalizeSpaces(body.Name), 1, 64); err != nil {
+		copyCustomErr := customerror.InvalidName
+		copyCustomErr.Message = err.Error()
+		ctx.ResponseWithCustomError(copyCustomErr)
+		return
+	}
+
+	key, token, err := rH.handler.CreateAPIKey(ctx, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.CreateAPIKey")
+		return
+	}
+
+	// The token is not retrievable later
+	ctx.WithData(map[string]interface{}{
+		"apiKey": key,
+		"token":  token,
+	}).Response(http.StatusCreated, "")
+	return
+}
+
+func (rH Handler) deleteAPIKeyHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.DeleteAPIKey(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.DeleteAPIKey")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
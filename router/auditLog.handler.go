@@ -0,0 +1,48 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/gin-gonic/gin"
+)
+
+// auditLogSortKeys maps the sortKey query to its column
+var auditLogSortKeys = map[string]string{
+	"id":        "id",
+	"actorId":   "actor_id",
+	"action":    "action",
+	"createdAt": "created_at",
+}
+
+func (rH Handler) listAuditLogHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var filter model.AuditLogFilter
+	if err := ctx.ShouldBindMQuery(&filter); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid Query")
+		return
+	}
+
+	paginator := ctx.GetPaginator()
+	sort := ctx.GetSortBy(auditLogSortKeys)
+	opt := model.EntityOption{
+		SortBy: sort,
+		Offset: &paginator.Offset,
+		Limit:  &paginator.Limit,
+	}
+
+	entries, total, err := rH.handler.ListAuditLogs(ctx, filter, opt)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListAuditLogs")
+		return
+	}
+
+	if sort != nil {
+		ctx.WithSort(ctx.GetSort())
+	}
+	paginator.SetTotalCount(int(total))
+	ctx.WithPaginator(paginator).WithData(entries).Response(http.StatusOK, "")
+	return
+}
@@ -2,10 +2,10 @@ package router
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/a5932016/go-ddd-example/customerror"
 	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/singleton/session"
 	"github.com/a5932016/go-ddd-example/util/log"
 	"github.com/a5932016/go-ddd-example/util/mGin"
 	"github.com/asaskevich/govalidator"
@@ -26,26 +26,59 @@ func (rH Handler) loginHandler(c *gin.Context) {
 		return
 	}
 
-	sessionId, user, err := rH.handler.Login(ctx, body.Account, body.Password)
+	client := session.ClientInfo{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+	result, err := rH.handler.Login(ctx, body.Account, body.Password, client)
 	if err != nil {
 		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.Login")
 		return
 	}
 
-	ctx.WithData(map[string]interface{}{
-		"sessionId": sessionId,
-		"user":      user,
-	}).Response(http.StatusOK, "")
-
+	rH.loginResponse(ctx, result)
 	return
 }
 
+// loginResponse responds with the signed in session, also set as cookies for browser clients,
+// with the tokens in the jwt strategy, or with the pre-auth token waiting for the second factor
+func (rH Handler) loginResponse(ctx *mGin.Context, result model.LoginResult) {
+	// The second factor upgrades the pre-auth token at /login/2fa
+	if result.PreAuthToken != "" {
+		ctx.WithData(map[string]interface{}{
+			"preAuthToken": result.PreAuthToken,
+			"twoFactor":    result.TwoFactor,
+		}).Response(http.StatusOK, "")
+		return
+	}
+
+	if result.AccessToken != "" {
+		tokenResponse(ctx, result)
+		return
+	}
+
+	csrfToken, err := rH.handler.IssueCSRFToken(ctx, result.SessionID)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.IssueCSRFToken")
+		return
+	}
+	rH.setSessionCookies(ctx, result.SessionID, csrfToken)
+
+	data := map[string]interface{}{
+		"sessionId": result.SessionID,
+		"csrfToken": csrfToken,
+		"user":      result.User,
+	}
+	if len(result.RecoveryCodes) > 0 {
+		data["recoveryCodes"] = result.RecoveryCodes
+	}
+	ctx.WithData(data).Response(http.StatusOK, "")
+}
+
 func (rH Handler) logoutHandler(c *gin.Context) {
 	ctx := mGin.NewContext(c)
 
 	if err := rH.handler.Logout(ctx); err != nil {
 		log.FromContext(c).WithError(err).Error("handler.Logout")
 	}
+	rH.clearSessionCookies(ctx)
 
 	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
 	return
@@ -81,6 +114,34 @@ func (rH Handler) forgotPasswordHandler(c *gin.Context) {
 	return
 }
 
+type requestPasswordResetBody struct {
+	Email string `json:"email" binding:"required"`
+}
+
+func (rH Handler) requestPasswordResetHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body requestPasswordResetBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if ok := govalidator.IsEmail(body.Email); !ok {
+		ctx.ResponseWithCustomError(customerror.InvalidEmail)
+		return
+	}
+
+	if err := rH.handler.RequestPasswordReset(ctx, body.Email, ctx.ClientIP()); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.RequestPasswordReset")
+		return
+	}
+
+	// Same response whether the account exists or not
+	ctx.WithData(struct{}{}).Response(http.StatusAccepted, "")
+	return
+}
+
 type resetPasswordQuery struct {
 	ResetToken string `form:"resetToken" binding:"required"`
 }
@@ -104,14 +165,8 @@ func (rH Handler) resetPasswordHandler(c *gin.Context) {
 		return
 	}
 
-	// Password
-	if err := model.ValidatePassword(body.Password); err != nil {
-		copyCustomErr := customerror.InvalidPassword
-		copyCustomErr.Message = err.Error()
-		ctx.ResponseWithCustomError(copyCustomErr)
-		return
-	}
-	if !strings.EqualFold(body.Password, body.PasswordConfirmation) {
+	// Password, checked against the policy by the handler
+	if body.Password != body.PasswordConfirmation {
 		ctx.ResponseWithCustomError(customerror.InvalidPasswordConfirmation)
 		return
 	}
@@ -124,3 +179,92 @@ func (rH Handler) resetPasswordHandler(c *gin.Context) {
 	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
 	return
 }
+
+type changePasswordBody struct {
+	CurrentPassword      string `json:"currentPassword" binding:"required"`
+	Password             string `json:"password" binding:"required"`
+	PasswordConfirmation string `json:"passwordConfirmation" binding:"required"`
+}
+
+func (rH Handler) changePasswordHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body changePasswordBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if body.Password != body.PasswordConfirmation {
+		ctx.ResponseWithCustomError(customerror.InvalidPasswordConfirmation)
+		return
+	}
+
+	if err := rH.handler.ChangePassword(ctx, body.CurrentPassword, body.Password); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.ChangePassword")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func tokenResponse(ctx *mGin.Context, result model.LoginResult) {
+	data := map[string]interface{}{
+		"accessToken":  result.AccessToken,
+		"refreshToken": result.RefreshToken,
+		"tokenType":    "Bearer",
+		"expiresIn":    result.ExpiresIn,
+		"user":         result.User,
+	}
+	if len(result.RecoveryCodes) > 0 {
+		data["recoveryCodes"] = result.RecoveryCodes
+	}
+	ctx.WithData(data).Response(http.StatusOK, "")
+}
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+func (rH Handler) refreshTokenHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body refreshTokenBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	result, err := rH.handler.RefreshTokens(ctx, body.RefreshToken)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.RefreshTokens")
+		return
+	}
+
+	tokenResponse(ctx, result)
+	return
+}
+
+func (rH Handler) revokeTokenHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body refreshTokenBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := rH.handler.RevokeRefreshToken(ctx, body.RefreshToken); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.RevokeRefreshToken")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+// jwksHandler publishes the public keys verifying the access tokens of the jwt strategy
+func (rH Handler) jwksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, rH.handler.JWKS())
+}
@@ -0,0 +1,186 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/gin-gonic/gin"
+)
+
+// divisionSortKeys maps the sortKey query to its column
+var divisionSortKeys = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+func (rH Handler) getDivisionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	division, err := rH.handler.GetDivision(ctx, boundIdURI.ID)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.GetDivision")
+		return
+	}
+
+	ctx.WithData(division).Response(http.StatusOK, "")
+	return
+}
+
+type listDivisionQuery struct {
+	Keyword *string `form:"keyword"`
+}
+
+func (rH Handler) listDivisionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var query listDivisionQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid Query")
+		return
+	}
+
+	paginator := ctx.GetPaginator()
+	sort := ctx.GetSortBy(divisionSortKeys)
+	opt := model.EntityOption{
+		Keyword: query.Keyword,
+		SortBy:  sort,
+		Offset:  &paginator.Offset,
+		Limit:   &paginator.Limit,
+	}
+
+	divisions, total, err := rH.handler.ListDivisions(ctx, opt)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListDivisions")
+		return
+	}
+
+	if sort != nil {
+		ctx.WithSort(ctx.GetSort())
+	}
+	paginator.SetTotalCount(int(total))
+	ctx.WithPaginator(paginator).WithData(divisions).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) createDivisionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body viewModel.CreateDivision
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if cErr, ok := validateDivisionName(&body.Name); !ok {
+		ctx.ResponseWithCustomError(cErr)
+		return
+	}
+
+	division, err := rH.handler.CreateDivision(ctx, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.CreateDivision")
+		return
+	}
+
+	ctx.WithData(division).Response(http.StatusCreated, "")
+	return
+}
+
+func (rH Handler) updateDivisionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body viewModel.UpdateDivision
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if cErr, ok := validateDivisionName(body.Name); !ok {
+		ctx.ResponseWithCustomError(cErr)
+		return
+	}
+
+	division, err := rH.handler.UpdateDivision(ctx, boundIdURI.ID, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UpdateDivision")
+		return
+	}
+
+	ctx.WithData(division).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) deleteDivisionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.DeleteDivision(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.DeleteDivision")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) updateDivisionUsersHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body viewModel.UpdateDivisionUsers
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	division, err := rH.handler.UpdateDivisionUsers(ctx, boundIdURI.ID, body.UserIDs)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UpdateDivisionUsers")
+		return
+	}
+
+	ctx.WithData(division).Response(http.StatusOK, "")
+	return
+}
+
+// validateDivisionName validates the division name, nil name is skipped
+func validateDivisionName(name *string) (mGin.CustomError, bool) {
+	if name == nil {
+		return mGin.CustomError{}, true
+	}
+	normalizedName := model.NormalizeSpaces(*name)
+	if err := model.ValidateStringLength("Name", normalizedName, 1, 64); err != nil {
+		copyCustomErr := customerror.InvalidName
+		copyCustomErr.Message = err.Error()
+		return copyCustomErr, false
+	}
+	if err := Validate.Var(normalizedName, "name-chars"); err != nil {
+		return customerror.InvalidName, false
+	}
+	return mGin.CustomError{}, true
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+)
+
+// getEntityRouter returns the routes of every registered entity, guarded by its resource
+func (rH Handler) getEntityRouter() (routes []appRouter) {
+	for _, registration := range rH.entities {
+		for _, route := range registration.Routes(rH.entityHandler) {
+			pair := allowancePair{Resource: registration.Resource(), Action: route.Action, RootOnly: route.RootOnly}
+			routes = append(routes, appRouter{route.Method, route.Path, pair, route.Handler})
+		}
+	}
+	return routes
+}
+
+// softDeleteEntities returns the registered entities kept in the trash when deleted
+func (rH Handler) softDeleteEntities() (entities []eGorm.Entity) {
+	for _, registration := range rH.entities {
+		if registration.SoftDelete() {
+			entities = append(entities, registration.Entity())
+		}
+	}
+	return entities
+}
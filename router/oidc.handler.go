@@ -0,0 +1,80 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateMaxAge matches the lifetime of the pending login in the usecase
+const oidcStateMaxAge = 600
+
+// oidcLoginHandler redirects the browser to the identity provider
+func (rH Handler) oidcLoginHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	authURL, state, err := rH.handler.OIDCLoginURL(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.OIDCLoginURL")
+		return
+	}
+
+	rH.setOIDCStateCookie(ctx, state, oidcStateMaxAge)
+	ctx.Redirect(http.StatusFound, authURL)
+	return
+}
+
+// oidcLinkHandler starts linking an identity to the request user, returning the identity provider URL to open
+func (rH Handler) oidcLinkHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	authURL, state, err := rH.handler.OIDCLinkURL(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.OIDCLinkURL")
+		return
+	}
+
+	rH.setOIDCStateCookie(ctx, state, oidcStateMaxAge)
+	ctx.WithData(map[string]interface{}{"authUrl": authURL}).Response(http.StatusOK, "")
+	return
+}
+
+// oidcCallbackHandler signs in the user returning from the identity provider
+func (rH Handler) oidcCallbackHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	// The state must come back to the browser which started the login
+	state := ctx.Query("state")
+	cookieState, err := ctx.Cookie(rH.oidcStateCookieName())
+	rH.setOIDCStateCookie(ctx, "", -1)
+	if len(state) == 0 || err != nil || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		ctx.ResponseWithCustomError(customerror.InvalidOIDCLogin)
+		return
+	}
+
+	// The identity provider reports a denied or failed login instead of a code
+	code := ctx.Query("code")
+	if ctx.Query("error") != "" || len(code) == 0 {
+		ctx.ResponseWithCustomError(customerror.InvalidOIDCLogin)
+		return
+	}
+
+	client := session.ClientInfo{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+	result, err := rH.handler.OIDCCallback(ctx, state, code, client)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.OIDCCallback")
+		return
+	}
+
+	if result.Linked {
+		ctx.WithData(map[string]interface{}{"user": result.User, "linked": true}).Response(http.StatusOK, "")
+		return
+	}
+
+	rH.loginResponse(ctx, result)
+	return
+}
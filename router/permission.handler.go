@@ -0,0 +1,58 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/gin-gonic/gin"
+)
+
+func (rH Handler) getPermissionCatalogHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+	ctx.WithData(rH.handler.GetPermissionCatalog(ctx)).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) getDivisionPermissionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	permissions, err := rH.handler.GetDivisionPermissions(ctx, boundIdURI.ID)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.GetDivisionPermissions")
+		return
+	}
+
+	ctx.WithData(permissions).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) updateDivisionPermissionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body []model.Permission
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	permissions, err := rH.handler.UpdateDivisionPermissions(ctx, boundIdURI.ID, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UpdateDivisionPermissions")
+		return
+	}
+
+	ctx.WithData(permissions).Response(http.StatusOK, "")
+	return
+}
@@ -0,0 +1,169 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/gin-gonic/gin"
+)
+
+type bindRoleURI struct {
+	ID   uint   `uri:"id" binding:"required,number"`
+	Role string `uri:"role" binding:"required,alphanum,max=32"`
+}
+
+type bindRoleUserURI struct {
+	bindRoleURI
+	UserID uint `uri:"userId" binding:"required,number"`
+}
+
+type bindRoleParentURI struct {
+	bindRoleURI
+	Parent string `uri:"parent" binding:"required,alphanum,max=32"`
+}
+
+func (rH Handler) listDivisionRolesHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	assignments, err := rH.handler.ListDivisionRoles(ctx, boundIdURI.ID)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListDivisionRoles")
+		return
+	}
+
+	ctx.WithData(assignments).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) assignDivisionRoleHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body viewModel.AssignDivisionRole
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := rH.handler.AssignDivisionRole(ctx, boundIdURI.ID, body); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.AssignDivisionRole")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) revokeDivisionRoleHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindRoleUserURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.RevokeDivisionRole(ctx, boundURI.ID, boundURI.Role, boundURI.UserID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RevokeDivisionRole")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) getDivisionRolePermissionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindRoleURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	permissions, err := rH.handler.GetDivisionRolePermissions(ctx, boundURI.ID, boundURI.Role)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.GetDivisionRolePermissions")
+		return
+	}
+
+	ctx.WithData(permissions).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) updateDivisionRolePermissionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindRoleURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body []model.Permission
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	permissions, err := rH.handler.UpdateDivisionRolePermissions(ctx, boundURI.ID, boundURI.Role, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UpdateDivisionRolePermissions")
+		return
+	}
+
+	ctx.WithData(permissions).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) addDivisionRoleInheritanceHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindRoleURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body viewModel.AddRoleInheritance
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := rH.handler.AddDivisionRoleInheritance(ctx, boundURI.ID, boundURI.Role, body.Parent); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.AddDivisionRoleInheritance")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) deleteDivisionRoleInheritanceHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindRoleParentURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.DeleteDivisionRoleInheritance(ctx, boundURI.ID, boundURI.Role, boundURI.Parent); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.DeleteDivisionRoleInheritance")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+)
+
+const csrfHeader = "X-CSRF-Token"
+
+func (rH Handler) sessionCookieName() string {
+	return rH.sessionManager.SessionName()
+}
+
+// csrfCookieName is readable by the browser script, which echoes it in the X-CSRF-Token header
+func (rH Handler) csrfCookieName() string {
+	return rH.sessionManager.SessionName() + "_csrf"
+}
+
+// getSessionID reads the session ID from the Authorization header, or from the session cookie for browser clients
+func (rH Handler) getSessionID(ctx *mGin.Context) (sid string, fromCookie bool) {
+	if sid := ctx.GetHeader("Authorization"); len(sid) > 0 {
+		return sid, false
+	}
+	if sid, err := ctx.Cookie(rH.sessionCookieName()); err == nil && len(sid) > 0 {
+		return sid, true
+	}
+	return "", false
+}
+
+func (rH Handler) setSessionCookies(ctx *mGin.Context, sid, csrfToken string) {
+	maxAge := int(rH.sessionManager.MaxLifeTime())
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     rH.sessionCookieName(),
+		Value:    sid,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Expires:  time.Now().Add(time.Duration(maxAge) * time.Second),
+		HttpOnly: true,
+		Secure:   config.Env.SessionAuth.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     rH.csrfCookieName(),
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Expires:  time.Now().Add(time.Duration(maxAge) * time.Second),
+		Secure:   config.Env.SessionAuth.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (rH Handler) clearSessionCookies(ctx *mGin.Context) {
+	for _, name := range []string{rH.sessionCookieName(), rH.csrfCookieName()} {
+		http.SetCookie(ctx.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Expires:  time.Unix(0, 0),
+			HttpOnly: name == rH.sessionCookieName(),
+			Secure:   config.Env.SessionAuth.CookieSecure,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// oidcStateCookieName binds the single sign-on login to the browser which started it
+func (rH Handler) oidcStateCookieName() string {
+	return rH.sessionManager.SessionName() + "_oidc_state"
+}
+
+func (rH Handler) setOIDCStateCookie(ctx *mGin.Context, state string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     rH.oidcStateCookieName(),
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Expires:  time.Now().Add(time.Duration(maxAge) * time.Second),
+		HttpOnly: true,
+		Secure:   config.Env.SessionAuth.CookieSecure,
+		// Lax lets the cookie follow the top level redirect back from the identity provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// verifyCSRF checks the double submitted token of a cookie authenticated request that changes state
+func (rH Handler) verifyCSRF(ctx *mGin.Context, sid string) error {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	token := ctx.GetHeader(csrfHeader)
+	cookieToken, err := ctx.Cookie(rH.csrfCookieName())
+	if len(token) == 0 || err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(cookieToken)) != 1 {
+		return customerror.InvalidCSRFToken
+	}
+
+	// The token must also be the one issued to this session
+	return rH.handler.VerifyCSRFToken(ctx, sid, token)
+}
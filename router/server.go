@@ -33,9 +33,21 @@ func validateNameChars(fl validator.FieldLevel) bool {
 
 // RunServer provide run http or https protocol.
 func (rH Handler) RunServer() (err error) {
-	if err = rH.perRepo.LoadPolicy(); err != nil {
+	if err = rH.perRepo.LoadPolicy(context.Background()); err != nil {
 		return
 	}
+	defer rH.perRepo.Close()
+
+	// Background jobs: session gc, audit log retention and entity trash retention
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	stopJobs := []func(){
+		startBackground(jobsCtx, rH.sessionManager.GC),
+		startBackground(jobsCtx, rH.handler.AuditLogRetention),
+		startBackground(jobsCtx, func(c context.Context) {
+			rH.entityHandler.TrashRetention(c, rH.softDeleteEntities())
+		}),
+	}
 
 	var (
 		httpSrv = &http.Server{
@@ -87,6 +99,15 @@ func (rH Handler) RunServer() (err error) {
 			}
 		}()
 
+		// Stop background jobs
+		for _, stop := range stopJobs {
+			finishCount++
+			go func() {
+				stop()
+				finishCh <- struct{}{}
+			}()
+		}
+
 		for {
 			select {
 			case f := <-finishCh:
@@ -116,14 +137,33 @@ func (rH Handler) RunServer() (err error) {
 	}
 }
 
+// startBackground runs fn in the background until parent is done,
+// and returns the function canceling its context and waiting for it to return
+func startBackground(parent context.Context, fn func(c context.Context)) (stop func()) {
+	c, cancel := context.WithCancel(parent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(c)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 func (rH Handler) routerEngine() *gin.Engine {
 	// set server mode
 	gin.SetMode(config.Env.Core.Mode)
 
 	r := gin.New()
 	r.RedirectTrailingSlash = false
+	// Cancel the queries of a request once its client goes away
+	r.ContextWithFallback = true
 	middleware := []gin.HandlerFunc{
 		gin.Recovery(),
+		mGin.RequestIDMiddleware(),
 		CORSMiddleware(),
 		RateLimitMiddleware(rH),
 		mGin.RequestBodyToContextMiddleware(),
@@ -137,14 +177,20 @@ func (rH Handler) routerEngine() *gin.Engine {
 	})
 
 	// app
-	routers := rH.getRouter()
-	for i := range routers {
-		r.Handle(
-			routers[i].method,
-			routers[i].endpoint,
-			rH.permissionMiddleware(routers[i].allowancePair).GinFunc(),
-			routers[i].worker,
-		)
+	for _, group := range rH.getRouterGroups() {
+		g := r.Group(group.prefix, group.middleware...)
+		for i := range group.routers {
+			authMiddleware := rH.permissionMiddleware(group.routers[i].allowancePair)
+			if group.public {
+				authMiddleware = rH.publicMiddleware()
+			}
+			g.Handle(
+				group.routers[i].method,
+				group.routers[i].endpoint,
+				authMiddleware.GinFunc(),
+				group.routers[i].worker,
+			)
+		}
 	}
 
 	return r
@@ -8,6 +8,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	apiV1Prefix = "/api/v1"
+)
+
+type appRouterGroup struct {
+	prefix     string
+	public     bool // skip session authentication
+	middleware []gin.HandlerFunc
+	routers    []appRouter
+}
+
 type appRouter struct {
 	method        string
 	endpoint      string
@@ -18,16 +29,113 @@ type appRouter struct {
 type allowancePair struct {
 	Resource            model.Resource
 	Action              model.Action
+	Domain              domainSource
 	RootOnly            bool
 	SelfPrivilege       bool // param ID required
 	SelfInterdictFilter bool // param ID required
 	HierarchyFilter     bool // param ID required
 }
 
+// domainSource is where the permission middleware takes the casbin domains of a route from
+type domainSource int
+
+const (
+	domainAny      domainSource = iota // global route, allowed by a permission in any division of the user
+	domainDivision                     // the division of param ID
+	domainUser                         // the divisions of the user of param ID
+)
+
+func (rH Handler) getRouterGroups() []appRouterGroup {
+	return []appRouterGroup{
+		// health
+		appRouterGroup{
+			public: true,
+			routers: []appRouter{
+				appRouter{http.MethodGet, "/health", allowancePair{}, rH.healthHandler},
+				appRouter{http.MethodGet, "/.well-known/jwks.json", allowancePair{}, rH.jwksHandler},
+			},
+		},
+		// auth
+		appRouterGroup{
+			prefix:     apiV1Prefix + "/auth",
+			public:     true,
+			middleware: []gin.HandlerFunc{NoStoreMiddleware()},
+			routers: []appRouter{
+				appRouter{http.MethodPost, "/login", allowancePair{}, rH.loginHandler},
+				appRouter{http.MethodPost, "/login/2fa", allowancePair{}, rH.verifyLoginTwoFactorHandler},
+				appRouter{http.MethodPost, "/login/2fa/enroll", allowancePair{}, rH.enrollLoginTwoFactorHandler},
+				appRouter{http.MethodPost, "/request-password-reset", allowancePair{}, rH.requestPasswordResetHandler},
+				appRouter{http.MethodPost, "/reset-password", allowancePair{}, rH.resetPasswordHandler},
+				appRouter{http.MethodPost, "/token/refresh", allowancePair{}, rH.refreshTokenHandler},
+				appRouter{http.MethodPost, "/token/revoke", allowancePair{}, rH.revokeTokenHandler},
+				appRouter{http.MethodGet, "/oidc/login", allowancePair{}, rH.oidcLoginHandler},
+				appRouter{http.MethodGet, "/oidc/callback", allowancePair{}, rH.oidcCallbackHandler},
+			},
+		},
+		appRouterGroup{
+			prefix:     apiV1Prefix + "/auth",
+			middleware: []gin.HandlerFunc{NoStoreMiddleware()},
+			routers: []appRouter{
+				appRouter{http.MethodPost, "/logout", allowancePair{}, rH.logoutHandler},
+				appRouter{http.MethodGet, "/sessions", allowancePair{}, rH.listSessionsHandler},
+				appRouter{http.MethodDelete, "/sessions", allowancePair{}, rH.revokeOtherSessionsHandler},
+				appRouter{http.MethodDelete, "/sessions/:handle", allowancePair{}, rH.revokeSessionHandler},
+				appRouter{http.MethodPost, "/forgot-password", allowancePair{RootOnly: true}, rH.forgotPasswordHandler},
+				appRouter{http.MethodPut, "/password", allowancePair{}, rH.changePasswordHandler},
+				appRouter{http.MethodPost, "/oidc/link", allowancePair{}, rH.oidcLinkHandler},
+				appRouter{http.MethodGet, "/api-keys", allowancePair{}, rH.listAPIKeysHandler},
+				appRouter{http.MethodPost, "/api-keys", allowancePair{}, rH.createAPIKeyHandler},
+				appRouter{http.MethodDelete, "/api-keys/:id", allowancePair{}, rH.deleteAPIKeyHandler},
+				appRouter{http.MethodPost, "/2fa/setup", allowancePair{}, rH.setupTwoFactorHandler},
+				appRouter{http.MethodPost, "/2fa/enable", allowancePair{}, rH.enableTwoFactorHandler},
+				appRouter{http.MethodPost, "/2fa/disable", allowancePair{}, rH.disableTwoFactorHandler},
+				appRouter{http.MethodPost, "/2fa/recovery-codes", allowancePair{}, rH.regenerateRecoveryCodesHandler},
+			},
+		},
+		// app
+		appRouterGroup{
+			prefix:  apiV1Prefix,
+			routers: append(rH.getRouter(), rH.getEntityRouter()...),
+		},
+	}
+}
+
 func (rH Handler) getRouter() (routes []appRouter) {
 	return []appRouter{
 		// user
-		appRouter{http.MethodGet, "/user/:id", allowancePair{Resource: model.ResourceUser, Action: model.ActionRead, SelfPrivilege: true}, rH.getUserHandler},
+		appRouter{http.MethodGet, "/user", allowancePair{Resource: model.ResourceUser, Action: model.ActionRead}, rH.listUserHandler},
+		appRouter{http.MethodPost, "/user", allowancePair{Resource: model.ResourceUser, Action: model.ActionCreate}, rH.createUserHandler},
+		appRouter{http.MethodGet, "/user/:id", allowancePair{Resource: model.ResourceUser, Action: model.ActionRead, Domain: domainUser, SelfPrivilege: true}, rH.getUserHandler},
+		appRouter{http.MethodPut, "/user/:id", allowancePair{Resource: model.ResourceUser, Action: model.ActionUpdate, Domain: domainUser, SelfPrivilege: true, HierarchyFilter: true}, rH.updateUserHandler},
+		appRouter{http.MethodDelete, "/user/:id", allowancePair{Resource: model.ResourceUser, Action: model.ActionDelete, Domain: domainUser, SelfInterdictFilter: true, HierarchyFilter: true}, rH.deleteUserHandler},
+		appRouter{http.MethodDelete, "/user/:id/lockout", allowancePair{Resource: model.ResourceUser, Action: model.ActionUpdate, Domain: domainUser, HierarchyFilter: true}, rH.unlockUserHandler},
+		appRouter{http.MethodPut, "/user/:id/2fa", allowancePair{Resource: model.ResourceUser, Action: model.ActionUpdate, RootOnly: true}, rH.requireTwoFactorHandler},
+		appRouter{http.MethodDelete, "/user/:id/sessions", allowancePair{Resource: model.ResourceUser, Action: model.ActionUpdate, Domain: domainUser, HierarchyFilter: true}, rH.revokeUserSessionsHandler},
+		appRouter{http.MethodPut, "/user/:id/restore", allowancePair{Resource: model.ResourceUser, Action: model.ActionDelete, RootOnly: true}, rH.restoreUserHandler},
+		appRouter{http.MethodDelete, "/user/:id/purge", allowancePair{Resource: model.ResourceUser, Action: model.ActionDelete, RootOnly: true, SelfInterdictFilter: true}, rH.purgeUserHandler},
+
+		// division
+		appRouter{http.MethodGet, "/division", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead}, rH.listDivisionHandler},
+		appRouter{http.MethodPost, "/division", allowancePair{Resource: model.ResourceDivision, Action: model.ActionCreate}, rH.createDivisionHandler},
+		appRouter{http.MethodGet, "/division/:id", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead, Domain: domainDivision}, rH.getDivisionHandler},
+		appRouter{http.MethodPut, "/division/:id", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, Domain: domainDivision}, rH.updateDivisionHandler},
+		appRouter{http.MethodDelete, "/division/:id", allowancePair{Resource: model.ResourceDivision, Action: model.ActionDelete, Domain: domainDivision}, rH.deleteDivisionHandler},
+		appRouter{http.MethodPut, "/division/:id/users", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.updateDivisionUsersHandler},
+		appRouter{http.MethodGet, "/division/:id/permissions", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead, Domain: domainDivision}, rH.getDivisionPermissionsHandler},
+		appRouter{http.MethodPut, "/division/:id/permissions", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.updateDivisionPermissionsHandler},
+		appRouter{http.MethodGet, "/division/:id/roles", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead, Domain: domainDivision}, rH.listDivisionRolesHandler},
+		appRouter{http.MethodPost, "/division/:id/roles", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.assignDivisionRoleHandler},
+		appRouter{http.MethodDelete, "/division/:id/roles/:role/users/:userId", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.revokeDivisionRoleHandler},
+		appRouter{http.MethodGet, "/division/:id/roles/:role/permissions", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead, Domain: domainDivision}, rH.getDivisionRolePermissionsHandler},
+		appRouter{http.MethodPut, "/division/:id/roles/:role/permissions", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.updateDivisionRolePermissionsHandler},
+		appRouter{http.MethodPost, "/division/:id/roles/:role/parents", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.addDivisionRoleInheritanceHandler},
+		appRouter{http.MethodDelete, "/division/:id/roles/:role/parents/:parent", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, RootOnly: true}, rH.deleteDivisionRoleInheritanceHandler},
+
+		// permission
+		appRouter{http.MethodGet, "/permissions/catalog", allowancePair{}, rH.getPermissionCatalogHandler},
+
+		// audit log
+		appRouter{http.MethodGet, "/audit-logs", allowancePair{RootOnly: true}, rH.listAuditLogHandler},
 	}
 }
 
@@ -1,12 +1,16 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/a5932016/go-ddd-example/config"
 	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/usecase"
 	"github.com/a5932016/go-ddd-example/util/mGin"
 	"github.com/gin-gonic/gin"
@@ -14,27 +18,25 @@ import (
 	limiterMiddleware "github.com/ulule/limiter/v3/drivers/middleware/gin"
 )
 
-func (rH Handler) permissionMiddleware(pair allowancePair) mGin.HandlerFunc {
+// publicMiddleware passes the optional session ID through without authentication
+func (rH Handler) publicMiddleware() mGin.HandlerFunc {
 	return func(ctx *mGin.Context) {
-		sid := ctx.GetHeader("Authorization")
-
-		// No permission needed
-		if pair == (allowancePair{}) {
-			ctx.Set(usecase.SID, sid)
-			ctx.Next()
-			return
-		}
+		sid, _ := rH.getSessionID(ctx)
+		ctx.Set(usecase.SID, sid)
+		ctx.Next()
+	}
+}
 
-		// Require Authorization
-		if !(len(sid) > 0) {
-			ctx.Response(http.StatusUnauthorized, "Require Authorization")
+func (rH Handler) permissionMiddleware(pair allowancePair) mGin.HandlerFunc {
+	return func(ctx *mGin.Context) {
+		requestUser, sid, apiKey, ok := rH.authenticate(ctx)
+		if !ok {
 			return
 		}
 
-		// Fetch request user
-		requestUser, err := rH.handler.GetRequestUserFromSID(sid)
-		if err != nil {
-			ctx.WithError(err).Response(http.StatusInternalServerError, "GetRequestUserFromSID")
+		// Scope Check: An API key reaches only the resource actions of its scopes, on top of its owner's permissions
+		if apiKey != nil && !apiKey.Allows(pair.Resource, pair.Action) {
+			ctx.ResponseWithCustomError(customerror.NoPermission)
 			return
 		}
 
@@ -82,22 +84,64 @@ func (rH Handler) permissionMiddleware(pair allowancePair) mGin.HandlerFunc {
 			return
 		}
 
-		// // Regular user permission check
-		// prefixedObj := pair.Resource.Prefix()
-		// prefixedAct := pair.Action.Prefix()
-		// for _, division := range requestUser.Divisions {
-		// 	ok, err := rH.perRepo.Enforce(division.GetPrefixedNameID(), prefixedObj, prefixedAct)
-		// 	if err != nil {
-		// 		ctx.WithError(err).Response(http.StatusInternalServerError,
-		// 			fmt.Sprintf("enforcer.Enforce(%s, %s, %s)", division.GetPrefixedNameID(), prefixedObj, prefixedAct))
-		// 		return
-		// 	}
-		// 	if ok {
-		// 		ctx.Set(usecase.SID, sid)
-		// 		ctx.Next()
-		// 		return
-		// 	}
-		// }
+		// Authentication only: No resource permission needed
+		if pair.Resource == "" {
+			ctx.Set(usecase.SID, sid)
+			ctx.Next()
+			return
+		}
+
+		// Domain Check: A route of a division or of a user is only allowed by the permissions in its divisions
+		targetDomains, err := rH.getTargetDomains(ctx, pair.Domain)
+		if err != nil {
+			return
+		}
+		inTarget := func(domain string) bool {
+			return pair.Domain == domainAny || slices.Contains(targetDomains, domain)
+		}
+
+		// Regular user permission check
+		prefixedObj := pair.Resource.Prefix()
+		prefixedAct := pair.Action.Prefix()
+		for _, division := range requestUser.Divisions {
+			if !inTarget(division.GetPrefixedNameID()) {
+				continue
+			}
+			ok, err := rH.perRepo.Enforce(ctx, division.GetPrefixedNameID(), division.GetPrefixedNameID(), prefixedObj, prefixedAct)
+			if err != nil {
+				ctx.WithError(err).Response(http.StatusInternalServerError,
+					fmt.Sprintf("enforcer.Enforce(%s, %s, %s)", division.GetPrefixedNameID(), prefixedObj, prefixedAct))
+				return
+			}
+			if ok {
+				ctx.Set(usecase.SID, sid)
+				ctx.Next()
+				return
+			}
+		}
+
+		// Role permission check: the user's roles in each division
+		domains, err := rH.perRepo.GetDomainsForUser(ctx, requestUser.GetPrefixedID())
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "perRepo.GetDomainsForUser")
+			return
+		}
+		for _, domain := range domains {
+			if !inTarget(domain) {
+				continue
+			}
+			ok, err := rH.perRepo.Enforce(ctx, requestUser.GetPrefixedID(), domain, prefixedObj, prefixedAct)
+			if err != nil {
+				ctx.WithError(err).Response(http.StatusInternalServerError,
+					fmt.Sprintf("enforcer.Enforce(%s, %s, %s, %s)", requestUser.GetPrefixedID(), domain, prefixedObj, prefixedAct))
+				return
+			}
+			if ok {
+				ctx.Set(usecase.SID, sid)
+				ctx.Next()
+				return
+			}
+		}
 
 		// Self Allowed Check: The account owner can change themself even without permission
 		if pair.SelfPrivilege {
@@ -118,6 +162,104 @@ func (rH Handler) permissionMiddleware(pair allowancePair) mGin.HandlerFunc {
 	}
 }
 
+// authenticate returns the request user of the API key, of the session,
+// or of the bearer access token in the jwt strategy.
+// It responds and returns false when the request is not authenticated.
+func (rH Handler) authenticate(ctx *mGin.Context) (requestUser model.User, sid string, apiKey *model.APIKey, ok bool) {
+	// Machine clients: the key acts as its owner
+	if token, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); found && model.IsAPIKeyToken(token) {
+		requestUser, key, err := rH.handler.GetRequestUserFromAPIKey(ctx, token)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "GetRequestUserFromAPIKey")
+			return model.User{}, "", nil, false
+		}
+		ctx.Set(model.ContextUser, requestUser)
+		return requestUser, "", &key, true
+	}
+
+	if config.Env.Auth.Strategy == "jwt" {
+		accessToken, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !found || !(len(accessToken) > 0) {
+			ctx.Response(http.StatusUnauthorized, "Require Authorization")
+			return model.User{}, "", nil, false
+		}
+
+		// The claims are the request user
+		requestUser, family, err := rH.handler.GetRequestUserFromAccessToken(ctx, accessToken)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "GetRequestUserFromAccessToken")
+			return model.User{}, "", nil, false
+		}
+		ctx.Set(model.ContextUser, requestUser)
+		ctx.Set(usecase.RefreshFamily, family)
+		return requestUser, "", nil, true
+	}
+
+	sid, fromCookie := rH.getSessionID(ctx)
+
+	// Require Authorization
+	if !(len(sid) > 0) {
+		ctx.Response(http.StatusUnauthorized, "Require Authorization")
+		return model.User{}, "", nil, false
+	}
+
+	// CSRF Check: Browsers send the cookie on cross-site requests too
+	if fromCookie {
+		if err := rH.verifyCSRF(ctx, sid); err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "verifyCSRF")
+			return model.User{}, "", nil, false
+		}
+	}
+
+	// Fetch request user, reloaded when it or the permissions changed since it was cached
+	requestUser, err := rH.handler.GetRequestUserFromSID(ctx, sid)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "GetRequestUserFromSID")
+		return model.User{}, "", nil, false
+	}
+	ctx.Set(model.ContextUser, requestUser)
+
+	return requestUser, sid, nil, true
+}
+
+// getTargetDomains returns the casbin domains of the route's target, none for a global route.
+// It responds and returns an error when the target can not be read.
+func (rH Handler) getTargetDomains(ctx *mGin.Context, source domainSource) ([]string, error) {
+	switch source {
+	case domainDivision:
+		divisionID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return nil, err
+		}
+		return []string{model.Division{ID: uint(divisionID)}.GetPrefixedNameID()}, nil
+
+	case domainUser:
+		aimingUserID, err := getUserIDFromParam(ctx)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return nil, err
+		}
+		user, err := rH.handler.GetUser(ctx, aimingUserID)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "handler.GetUser")
+			return nil, err
+		}
+		// The divisions the user is a member of, and those where the user has a role
+		domains, err := rH.perRepo.GetDomainsForUser(ctx, user.GetPrefixedID())
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "perRepo.GetDomainsForUser")
+			return nil, err
+		}
+		for _, division := range user.Divisions {
+			domains = append(domains, division.GetPrefixedNameID())
+		}
+		return domains, nil
+	}
+
+	return nil, nil
+}
+
 func getUserIDFromParam(ctx *mGin.Context) (uint, error) {
 	aimingUserID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
@@ -126,11 +268,21 @@ func getUserIDFromParam(ctx *mGin.Context) (uint, error) {
 	return uint(aimingUserID), nil
 }
 
+// NoStoreMiddleware prevents credentials in responses from being cached
+func NoStoreMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Set("Cache-Control", "no-store")
+		c.Writer.Header().Set("Pragma", "no-cache")
+		c.Next()
+	}
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// github.com/gin-contrib/cors
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-Request-Id, Authorization, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
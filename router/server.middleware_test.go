@@ -0,0 +1,124 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository/casbin"
+	"github.com/a5932016/go-ddd-example/usecase"
+)
+
+// testHandler authenticates every session as the request user and knows the users by ID
+type testHandler struct {
+	usecase.Handler
+	requestUser model.User
+	users       map[uint]model.User
+}
+
+func (h testHandler) GetRequestUserFromSID(c context.Context, sessionID string) (model.User, error) {
+	return h.requestUser, nil
+}
+
+func (h testHandler) GetUser(c context.Context, id uint) (model.User, error) {
+	user, ok := h.users[id]
+	if !ok {
+		return model.User{}, customerror.RecordNotFound
+	}
+	return user, nil
+}
+
+func newTestPERRepository(t *testing.T) *casbin.PERRepository {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	perRepo, err := casbin.NewPERRepository(db, "../casbin.conf", "casbin_rules")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return perRepo
+}
+
+func TestPermissionMiddlewareDomain(t *testing.T) {
+	ctx := context.Background()
+	perRepo := newTestPERRepository(t)
+
+	// An editor in div:1 and a viewer in div:2
+	editor, viewer := model.GetPrefixedRole("editor"), model.GetPrefixedRole("viewer")
+	div1, div2 := model.Division{ID: 1}.GetPrefixedNameID(), model.Division{ID: 2}.GetPrefixedNameID()
+	var rules [][]string
+	for _, domain := range []string{div1, div2} {
+		for _, resource := range []model.Resource{model.ResourceDivision, model.ResourceUser} {
+			rules = append(rules,
+				[]string{editor, domain, resource.Prefix(), model.ActionRead.Prefix()},
+				[]string{editor, domain, resource.Prefix(), model.ActionUpdate.Prefix()},
+				[]string{viewer, domain, resource.Prefix(), model.ActionRead.Prefix()},
+			)
+		}
+	}
+	assert.NoError(t, perRepo.AddPoliciesEx(ctx, rules))
+	requestUser := model.User{ID: 1}
+	assert.NoError(t, perRepo.AddRoleForUserInDomain(ctx, requestUser.GetPrefixedID(), editor, div1))
+	assert.NoError(t, perRepo.AddRoleForUserInDomain(ctx, requestUser.GetPrefixedID(), viewer, div2))
+
+	rH := Handler{
+		handler: testHandler{
+			requestUser: requestUser,
+			users: map[uint]model.User{
+				2: {ID: 2, Divisions: []model.Division{{ID: 1}}},
+				3: {ID: 3, Divisions: []model.Division{{ID: 2}}},
+				4: {ID: 4},
+			},
+		},
+		perRepo: perRepo,
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, route := range []appRouter{
+		{http.MethodPut, "/division", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate}, nil},
+		{http.MethodGet, "/division/:id", allowancePair{Resource: model.ResourceDivision, Action: model.ActionRead, Domain: domainDivision}, nil},
+		{http.MethodPut, "/division/:id", allowancePair{Resource: model.ResourceDivision, Action: model.ActionUpdate, Domain: domainDivision}, nil},
+		{http.MethodPut, "/user/:id", allowancePair{Resource: model.ResourceUser, Action: model.ActionUpdate, Domain: domainUser}, nil},
+	} {
+		engine.Handle(route.method, route.endpoint, rH.permissionMiddleware(route.allowancePair).GinFunc(), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+	}{
+		{name: "global route by a role in any division", method: http.MethodPut, path: "/division", code: http.StatusNoContent},
+		{name: "update the division of the editor", method: http.MethodPut, path: "/division/1", code: http.StatusNoContent},
+		{name: "update the division of the viewer", method: http.MethodPut, path: "/division/2", code: customerror.NoPermission.HTTPCode},
+		{name: "read the division of the viewer", method: http.MethodGet, path: "/division/2", code: http.StatusNoContent},
+		{name: "read a division without a role", method: http.MethodGet, path: "/division/3", code: customerror.NoPermission.HTTPCode},
+		{name: "invalid division", method: http.MethodGet, path: "/division/x", code: http.StatusBadRequest},
+		{name: "update a user of the editor's division", method: http.MethodPut, path: "/user/2", code: http.StatusNoContent},
+		{name: "update a user of the viewer's division", method: http.MethodPut, path: "/user/3", code: customerror.NoPermission.HTTPCode},
+		{name: "update a user without a division", method: http.MethodPut, path: "/user/4", code: customerror.NoPermission.HTTPCode},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "sid")
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
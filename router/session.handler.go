@@ -0,0 +1,79 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/gin-gonic/gin"
+)
+
+type bindSessionURI struct {
+	Handle string `uri:"handle" binding:"required,hexadecimal"`
+}
+
+func (rH Handler) listSessionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	sessions, err := rH.handler.ListSessions(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListSessions")
+		return
+	}
+
+	ctx.WithData(sessions).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) revokeSessionHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundURI bindSessionURI
+	if err := ctx.ShouldBindUri(&boundURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.RevokeSession(ctx, boundURI.Handle); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RevokeSession")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) revokeOtherSessionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	revoked, err := rH.handler.RevokeOtherSessions(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RevokeOtherSessions")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"revoked": revoked,
+	}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) revokeUserSessionsHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	revoked, err := rH.handler.RevokeUserSessions(ctx, boundIdURI.ID)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RevokeUserSessions")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"revoked": revoked,
+	}).Response(http.StatusOK, "")
+	return
+}
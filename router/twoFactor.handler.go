@@ -0,0 +1,168 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/gin-gonic/gin"
+)
+
+type preAuthBody struct {
+	PreAuthToken string `json:"preAuthToken" binding:"required"`
+}
+
+type verifyLoginTwoFactorBody struct {
+	PreAuthToken string `json:"preAuthToken" binding:"required"`
+	// Code is an authenticator code, or a recovery code
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type twoFactorCodeBody struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type requireTwoFactorBody struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+func (rH Handler) verifyLoginTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body verifyLoginTwoFactorBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	client := session.ClientInfo{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent()}
+	result, err := rH.handler.VerifyLoginTwoFactor(ctx, body.PreAuthToken, body.Code, client)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.VerifyLoginTwoFactor")
+		return
+	}
+
+	rH.loginResponse(ctx, result)
+	return
+}
+
+func (rH Handler) enrollLoginTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body preAuthBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	secret, uri, err := rH.handler.EnrollLoginTwoFactor(ctx, body.PreAuthToken)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "handler.EnrollLoginTwoFactor")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"secret": secret,
+		"uri":    uri,
+	}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) setupTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	secret, uri, err := rH.handler.SetupTwoFactor(ctx)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.SetupTwoFactor")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"secret": secret,
+		"uri":    uri,
+	}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) enableTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body twoFactorCodeBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	recoveryCodes, err := rH.handler.EnableTwoFactor(ctx, body.Code)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.EnableTwoFactor")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) disableTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body twoFactorCodeBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := rH.handler.DisableTwoFactor(ctx, body.Code); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.DisableTwoFactor")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) regenerateRecoveryCodesHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body twoFactorCodeBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	recoveryCodes, err := rH.handler.RegenerateRecoveryCodes(ctx, body.Code)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RegenerateRecoveryCodes")
+		return
+	}
+
+	ctx.WithData(map[string]interface{}{
+		"recoveryCodes": recoveryCodes,
+	}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) requireTwoFactorHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body requireTwoFactorBody
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := rH.handler.RequireTwoFactor(ctx, boundIdURI.ID, *body.Required); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RequireTwoFactor")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
@@ -3,7 +3,11 @@ package router
 import (
 	"net/http"
 
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/util/mGin"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/asaskevich/govalidator"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,6 +15,16 @@ type bindIdURI struct {
 	ID uint `uri:"id" binding:"required,number"`
 }
 
+// userSortKeys maps the sortKey query to its column
+var userSortKeys = map[string]string{
+	"id":        "id",
+	"email":     "email",
+	"name":      "name",
+	"isRoot":    "is_root",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
 func (rH Handler) getUserHandler(c *gin.Context) {
 	ctx := mGin.NewContext(c)
 	var boundIdURI bindIdURI
@@ -29,3 +43,181 @@ func (rH Handler) getUserHandler(c *gin.Context) {
 	ctx.WithData(user).Response(http.StatusOK, "")
 	return
 }
+
+type listUserQuery struct {
+	Keyword        *string `form:"keyword"`
+	IncludeDeleted bool    `form:"includeDeleted"`
+}
+
+func (rH Handler) listUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var query listUserQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid Query")
+		return
+	}
+
+	paginator := ctx.GetPaginator()
+	sort := ctx.GetSortBy(userSortKeys)
+	opt := model.EntityOption{
+		Keyword:        query.Keyword,
+		SortBy:         sort,
+		IncludeDeleted: query.IncludeDeleted,
+		Offset:         &paginator.Offset,
+		Limit:          &paginator.Limit,
+	}
+
+	users, total, err := rH.handler.ListUsers(ctx, opt)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.ListUsers")
+		return
+	}
+
+	if sort != nil {
+		ctx.WithSort(ctx.GetSort())
+	}
+	paginator.SetTotalCount(int(total))
+	ctx.WithPaginator(paginator).WithData(users).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) createUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var body viewModel.CreateUser
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if cErr, ok := validateUserProfile(&body.Email, &body.Name); !ok {
+		ctx.ResponseWithCustomError(cErr)
+		return
+	}
+
+	user, err := rH.handler.CreateUser(ctx, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.CreateUser")
+		return
+	}
+
+	ctx.WithData(user).Response(http.StatusCreated, "")
+	return
+}
+
+func (rH Handler) updateUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+	var body viewModel.UpdateUser
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if cErr, ok := validateUserProfile(body.Email, body.Name); !ok {
+		ctx.ResponseWithCustomError(cErr)
+		return
+	}
+
+	user, err := rH.handler.UpdateUser(ctx, boundIdURI.ID, body)
+	if err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UpdateUser")
+		return
+	}
+
+	ctx.WithData(user).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) deleteUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.DeleteUser(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.DeleteUser")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) restoreUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.RestoreUser(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.RestoreUser")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) purgeUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.PurgeUser(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.PurgeUser")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+func (rH Handler) unlockUserHandler(c *gin.Context) {
+	ctx := mGin.NewContext(c)
+
+	var boundIdURI bindIdURI
+	if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+		ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+		return
+	}
+
+	if err := rH.handler.UnlockUser(ctx, boundIdURI.ID); err != nil {
+		ctx.WithError(err).Response(http.StatusInternalServerError, "rH.handler.UnlockUser")
+		return
+	}
+
+	ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+	return
+}
+
+// validateUserProfile validates the given profile fields, nil fields are skipped
+func validateUserProfile(email, name *string) (mGin.CustomError, bool) {
+	if email != nil && !govalidator.IsEmail(*email) {
+		return customerror.InvalidEmail, false
+	}
+	if name != nil {
+		if err := model.ValidateStringLength("Name", model.NormalizeSpaces(*name), 1, 64); err != nil {
+			copyCustomErr := customerror.InvalidUserName
+			copyCustomErr.Message = err.Error()
+			return copyCustomErr, false
+		}
+	}
+	return mGin.CustomError{}, true
+}
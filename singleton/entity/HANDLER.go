@@ -1,30 +1,30 @@
 package entity
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
 )
 
-type RegisterOpt[T any] struct {
-	Entity        eGorm.Entity
-	ListQueryFunc eGorm.ListQueryFunc
-}
-
-func NewEntityHandler(db *gorm.DB, opts []RegisterOpt[any]) *EntityHandler {
+func NewEntityHandler(db *gorm.DB, registrations []Registration) *EntityHandler {
 	entityGORMs := make(map[string]eGorm.EntityGORM[eGorm.Entity])
-	for _, opt := range opts {
-		entityGORMs[opt.Entity.ModelName()] = eGorm.NewEntityGORM(db, opt.Entity, opt.ListQueryFunc)
+	for _, registration := range registrations {
+		entity := registration.Entity()
+		entityGORMs[entity.ModelName()] = eGorm.NewEntityGORM(db, entity, registration.listQuery)
 	}
 	return &EntityHandler{
-		opts:        opts,
-		entityGORMs: entityGORMs,
+		db:            db,
+		registrations: registrations,
+		entityGORMs:   entityGORMs,
 	}
 }
 
 type EntityHandler struct {
-	opts        []RegisterOpt[any]
-	entityGORMs map[string]eGorm.EntityGORM[eGorm.Entity]
+	db            *gorm.DB
+	registrations []Registration
+	entityGORMs   map[string]eGorm.EntityGORM[eGorm.Entity]
 }
 
 func (h *EntityHandler) Entity(entity eGorm.Entity) EGORM {
@@ -36,6 +36,12 @@ func (h *EntityHandler) Entity(entity eGorm.Entity) EGORM {
 	return eGorm.NewErrorEntityGORM(entity)
 }
 
+// Begin returns the entity handler querying db, the transaction of a unit of work
 func (h *EntityHandler) Begin(db *gorm.DB) *EntityHandler {
-	return NewEntityHandler(db, h.opts)
+	return NewEntityHandler(db, h.registrations)
+}
+
+// WithContext returns the entity handler whose queries are bound to c, for cancellation and tracing
+func (h *EntityHandler) WithContext(c context.Context) *EntityHandler {
+	return NewEntityHandler(h.db.WithContext(c), h.registrations)
 }
@@ -1,10 +1,15 @@
 package entity
 
+import "time"
+
 type EGORM interface {
 	Get(dist any, id uint) error
 	List(dist any, opt any) error
 	Count(dist *int64, opt any) error
 	Create(dist any) error
 	Update(dist any, id uint) error
-	Delete(id uint) error
+	Delete(id uint, version *uint) error
+	Restore(id uint) error
+	Purge(id uint) error
+	PurgeDeleted(before time.Time) (purged int64, err error)
 }
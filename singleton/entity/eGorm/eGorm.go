@@ -1,15 +1,43 @@
 package eGorm
 
 import (
+	"errors"
+	"reflect"
+	"time"
+
 	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/model"
 )
 
+// ErrVersionConflict is returned by Update when the versioned entity is no longer at the version of the update
+var ErrVersionConflict = errors.New("version conflict")
+
 type Entity interface {
 	ModelName() string
 }
 
 type ListQueryFunc func(*gorm.DB, any) *gorm.DB
 
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// IsSoftDelete reports whether the entity has a gorm.DeletedAt field, so its deletion only sets it
+func IsSoftDelete(entity Entity) bool {
+	entityType := reflect.TypeOf(entity)
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	if entityType.Kind() != reflect.Struct {
+		return false
+	}
+	for _, field := range reflect.VisibleFields(entityType) {
+		if field.Type == deletedAtType {
+			return true
+		}
+	}
+	return false
+}
+
 func NewEntityGORM[T Entity](db *gorm.DB, entity T, listQueryFn ListQueryFunc) EntityGORM[T] {
 	return EntityGORM[T]{
 		db:          db,
@@ -43,14 +71,85 @@ func (e EntityGORM[T]) getDB(opt any) *gorm.DB {
 	return e.listQueryFn(e.db, opt)
 }
 
+// Create creates dist, starting a versioned entity at version 1
 func (e EntityGORM[T]) Create(dist any) error {
+	if versioned, ok := dist.(model.Versioned); ok && versioned.GetVersion() == 0 {
+		versioned.SetVersion(1)
+	}
 	return e.db.Create(dist).Error
 }
 
+// Update updates the entity of id to dist.
+// A versioned entity is only updated at the version of dist, which is bumped, otherwise ErrVersionConflict is returned.
 func (e EntityGORM[T]) Update(dist any, id uint) error {
-	return e.db.Where("id = ?", id).Updates(dist).Error
+	versioned, ok := dist.(model.Versioned)
+	if !ok {
+		return e.db.Where("id = ?", id).Updates(dist).Error
+	}
+
+	version := versioned.GetVersion()
+	versioned.SetVersion(version + 1)
+	result := e.db.Where("id = ? AND version = ?", id, version).Updates(dist)
+	if result.Error != nil {
+		versioned.SetVersion(version)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		versioned.SetVersion(version)
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Delete deletes the entity of id, only at the version when given
+func (e EntityGORM[T]) Delete(id uint, version *uint) error {
+	db := e.db.Where("id = ?", id)
+	if version != nil {
+		db = db.Where("version = ?", *version)
+	}
+	result := db.Delete(&e.entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore undeletes the soft-deleted entity of id, bumping the version of a versioned entity
+func (e EntityGORM[T]) Restore(id uint) error {
+	updates := map[string]interface{}{"deleted_at": nil}
+	if _, ok := reflect.New(reflect.TypeOf(e.entity)).Interface().(model.Versioned); ok {
+		updates["version"] = gorm.Expr("version + 1")
+	}
+
+	result := e.db.Unscoped().Model(&e.entity).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes the entity of id, soft-deleted or not
+func (e EntityGORM[T]) Purge(id uint) error {
+	result := e.db.Unscoped().Where("id = ?", id).Delete(&e.entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (e EntityGORM[T]) Delete(id uint) error {
-	return e.db.Where("id = ?", id).Delete(&e.entity).Error
+// PurgeDeleted permanently deletes the entities soft-deleted before the time
+func (e EntityGORM[T]) PurgeDeleted(before time.Time) (purged int64, err error) {
+	result := e.db.Unscoped().Where("deleted_at < ?", before).Delete(&e.entity)
+	return result.RowsAffected, result.Error
 }
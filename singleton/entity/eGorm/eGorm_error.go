@@ -2,6 +2,7 @@ package eGorm
 
 import (
 	"fmt"
+	"time"
 )
 
 func NewErrorEntityGORM[T Entity](entity T) ErrorEntityGORM[T] {
@@ -34,10 +35,22 @@ func (e ErrorEntityGORM[T]) Update(dist any, id uint) error {
 	return errFn(e.entity)
 }
 
-func (e ErrorEntityGORM[T]) Delete(id uint) error {
+func (e ErrorEntityGORM[T]) Delete(id uint, version *uint) error {
 	return errFn(e.entity)
 }
 
+func (e ErrorEntityGORM[T]) Restore(id uint) error {
+	return errFn(e.entity)
+}
+
+func (e ErrorEntityGORM[T]) Purge(id uint) error {
+	return errFn(e.entity)
+}
+
+func (e ErrorEntityGORM[T]) PurgeDeleted(before time.Time) (int64, error) {
+	return 0, errFn(e.entity)
+}
+
 var errFn = func(entity Entity) error {
 	return fmt.Errorf("Entity %s isn't registered", entity.ModelName())
 }
@@ -0,0 +1,133 @@
+package eGorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/model"
+)
+
+// newTestEntityGORM returns the announcements of an in-memory database
+func newTestEntityGORM(t *testing.T) EntityGORM[Entity] {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, db.AutoMigrate(&model.Announcement{})) {
+		t.FailNow()
+	}
+	return NewEntityGORM[Entity](db, model.Announcement{}, nil)
+}
+
+func getAnnouncement(t *testing.T, e EntityGORM[Entity], id uint) model.Announcement {
+	var announcement model.Announcement
+	assert.NoError(t, e.db.Unscoped().First(&announcement, id).Error)
+	return announcement
+}
+
+func TestUpdateVersion(t *testing.T) {
+	e := newTestEntityGORM(t)
+
+	announcement := model.Announcement{Title: "created"}
+	assert.NoError(t, e.Create(&announcement))
+	assert.EqualValues(t, 1, announcement.Version)
+
+	// Two writers read the entity at the same version
+	first, second := getAnnouncement(t, e, announcement.ID), getAnnouncement(t, e, announcement.ID)
+
+	first.Title = "first"
+	assert.NoError(t, e.Update(&first, first.ID))
+	assert.EqualValues(t, 2, first.Version)
+
+	second.Title = "second"
+	assert.ErrorIs(t, e.Update(&second, second.ID), ErrVersionConflict)
+	assert.EqualValues(t, 1, second.Version, "the version of a failed update is kept")
+
+	current := getAnnouncement(t, e, announcement.ID)
+	assert.Equal(t, "first", current.Title)
+	assert.EqualValues(t, 2, current.Version)
+
+	// Updated again at the current version
+	current.Title = "second"
+	assert.NoError(t, e.Update(&current, current.ID))
+	assert.EqualValues(t, 3, getAnnouncement(t, e, announcement.ID).Version)
+}
+
+func TestDeleteVersion(t *testing.T) {
+	e := newTestEntityGORM(t)
+
+	announcement := model.Announcement{Title: "created"}
+	assert.NoError(t, e.Create(&announcement))
+
+	stale, current := uint(0), uint(1)
+	assert.ErrorIs(t, e.Delete(announcement.ID, &stale), gorm.ErrRecordNotFound)
+	assert.NoError(t, e.Delete(announcement.ID, &current))
+	assert.ErrorIs(t, e.Delete(announcement.ID, nil), gorm.ErrRecordNotFound, "already deleted")
+}
+
+func TestRestore(t *testing.T) {
+	e := newTestEntityGORM(t)
+
+	announcement := model.Announcement{Title: "created"}
+	assert.NoError(t, e.Create(&announcement))
+	assert.ErrorIs(t, e.Restore(announcement.ID), gorm.ErrRecordNotFound, "a live entity is not in the trash")
+
+	assert.NoError(t, e.Delete(announcement.ID, nil))
+	assert.NoError(t, e.Restore(announcement.ID))
+
+	restored := getAnnouncement(t, e, announcement.ID)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.EqualValues(t, 2, restored.Version, "the version is bumped")
+	assert.ErrorIs(t, e.Restore(announcement.ID), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, e.Restore(announcement.ID+1), gorm.ErrRecordNotFound)
+}
+
+func TestPurge(t *testing.T) {
+	e := newTestEntityGORM(t)
+
+	live, trashed := model.Announcement{Title: "live"}, model.Announcement{Title: "trashed"}
+	assert.NoError(t, e.Create(&live))
+	assert.NoError(t, e.Create(&trashed))
+	assert.NoError(t, e.Delete(trashed.ID, nil))
+
+	assert.NoError(t, e.Purge(live.ID))
+	assert.NoError(t, e.Purge(trashed.ID))
+	assert.ErrorIs(t, e.Purge(live.ID), gorm.ErrRecordNotFound)
+
+	var count int64
+	assert.NoError(t, e.db.Unscoped().Model(&model.Announcement{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	e := newTestEntityGORM(t)
+	cutoff := time.Now()
+
+	titles := []string{"live", "deleted before", "deleted after"}
+	announcements := make([]model.Announcement, len(titles))
+	for i, title := range titles {
+		announcements[i] = model.Announcement{Title: title}
+		assert.NoError(t, e.Create(&announcements[i]))
+	}
+	for id, deletedAt := range map[uint]time.Time{
+		announcements[1].ID: cutoff.Add(-time.Hour),
+		announcements[2].ID: cutoff.Add(time.Hour),
+	} {
+		assert.NoError(t, e.db.Model(&model.Announcement{}).Where("id = ?", id).Update("deleted_at", deletedAt).Error)
+	}
+
+	purged, err := e.PurgeDeleted(cutoff)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+
+	var kept []model.Announcement
+	assert.NoError(t, e.db.Unscoped().Order("id").Find(&kept).Error)
+	if assert.Len(t, kept, 2) {
+		assert.Equal(t, "live", kept[0].Title)
+		assert.Equal(t, "deleted after", kept[1].Title)
+	}
+}
@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+	"github.com/a5932016/go-ddd-example/util/mGorm"
+)
+
+// DTO is the request body written to an entity T
+type DTO[T any] interface {
+	ToEntity() T
+}
+
+// Definition describes an entity T served by the generic entity routes,
+// listed by the query string filter O and written from the request body D
+type Definition[T eGorm.Entity, O any, D DTO[T]] struct {
+	// Name is the path of the routes, e.g. /announcement
+	Name string
+	// Resource is the permission resource of the routes
+	Resource model.Resource
+	// ListQuery applies the filter to the list query, the whole table is listed without it
+	ListQuery func(db *gorm.DB, filter O) *gorm.DB
+	// SortKeys maps the sortKey query to its column
+	SortKeys map[string]string
+}
+
+// Registration is a registered entity with its types erased, so entities of any types can be served together.
+// Its routes are built by the registration itself, so they bind and return its own types.
+type Registration interface {
+	Name() string
+	Resource() model.Resource
+	// Entity is the zero entity, identifying the entity by its model name
+	Entity() eGorm.Entity
+	// SoftDelete reports whether deleted entities are kept in the trash, to be restored or purged
+	SoftDelete() bool
+	// Routes returns the REST routes of the entity calling the use case
+	Routes(useCase UseCase) []Route
+
+	listQuery(db *gorm.DB, opt any) *gorm.DB
+}
+
+// ListOption lists an entity by its filter, a pointer to the filter of its definition, in the order and page of the entity option.
+// IncludeDeleted and OnlyDeleted of the entity option list the trash of a soft-deleted entity.
+type ListOption struct {
+	Filter any
+	model.EntityOption
+}
+
+// Register registers the entity of the definition
+func Register[T eGorm.Entity, O any, D DTO[T]](def Definition[T, O, D]) Registration {
+	return registration[T, O, D]{def: def}
+}
+
+type registration[T eGorm.Entity, O any, D DTO[T]] struct {
+	def Definition[T, O, D]
+}
+
+func (r registration[T, O, D]) Name() string {
+	return r.def.Name
+}
+
+func (r registration[T, O, D]) Resource() model.Resource {
+	return r.def.Resource
+}
+
+func (r registration[T, O, D]) Entity() eGorm.Entity {
+	var entity T
+	return entity
+}
+
+func (r registration[T, O, D]) SoftDelete() bool {
+	return eGorm.IsSoftDelete(r.Entity())
+}
+
+// fromDTO returns the entity written from the request body
+func (r registration[T, O, D]) fromDTO(dto D) *T {
+	entity := dto.ToEntity()
+	return &entity
+}
+
+func (r registration[T, O, D]) listQuery(db *gorm.DB, opt any) *gorm.DB {
+	listOpt, _ := opt.(ListOption)
+	if filter, ok := listOpt.Filter.(*O); ok && filter != nil && r.def.ListQuery != nil {
+		db = r.def.ListQuery(db, *filter)
+	}
+
+	if r.SoftDelete() {
+		switch {
+		case listOpt.OnlyDeleted:
+			db = db.Unscoped().Where("deleted_at IS NOT NULL")
+		case listOpt.IncludeDeleted:
+			db = db.Unscoped()
+		}
+	} else if listOpt.OnlyDeleted {
+		// Nothing is in the trash of a hard-deleted entity
+		db = db.Where("1 = 0")
+	}
+
+	mDB := mGorm.New(db).OrderWithFilter(listOpt.SortBy)
+	if listOpt.Offset != nil && listOpt.Limit != nil {
+		mDB.DB = mDB.DB.Limit(*listOpt.Limit).Offset(*listOpt.Offset)
+	}
+	return mDB.DB
+}
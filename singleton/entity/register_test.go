@@ -0,0 +1,235 @@
+package entity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+)
+
+type testNote struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+func (n testNote) ModelName() string {
+	return "note"
+}
+
+type testTrashNote struct {
+	ID        uint           `json:"id"`
+	Title     string         `json:"title"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt"`
+}
+
+func (n testTrashNote) ModelName() string {
+	return "trash_note"
+}
+
+type testNoteFilter struct {
+	Title string
+}
+
+type testNoteDTO struct {
+	Title string `json:"title"`
+}
+
+func (d testNoteDTO) ToEntity() testNote {
+	return testNote{Title: strings.TrimSpace(d.Title)}
+}
+
+type testTrashNoteDTO struct {
+	Title string `json:"title"`
+}
+
+func (d testTrashNoteDTO) ToEntity() testTrashNote {
+	return testTrashNote{Title: d.Title}
+}
+
+func filterByTitle(db *gorm.DB, filter testNoteFilter) *gorm.DB {
+	if filter.Title != "" {
+		db = db.Where("title = ?", filter.Title)
+	}
+	return db
+}
+
+var (
+	noteRegistration = registration[testNote, testNoteFilter, testNoteDTO]{def: Definition[testNote, testNoteFilter, testNoteDTO]{
+		Name:      "note",
+		Resource:  model.Resource("note"),
+		ListQuery: filterByTitle,
+	}}
+	trashNoteRegistration = registration[testTrashNote, testNoteFilter, testTrashNoteDTO]{def: Definition[testTrashNote, testNoteFilter, testTrashNoteDTO]{
+		Name:      "trash_note",
+		Resource:  model.Resource("trash_note"),
+		ListQuery: filterByTitle,
+	}}
+)
+
+// listSQL returns the SQL of listing the entity of the registration, without connecting to a database
+func listSQL(t *testing.T, r Registration, dist any, opt ListOption) string {
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return r.listQuery(db.Model(dist), opt).Find(dist).Statement.SQL.String()
+}
+
+func TestListQuery(t *testing.T) {
+	testCases := []struct {
+		name         string
+		registration Registration
+		dist         any
+		opt          ListOption
+		contains     []string
+		excludes     []string
+	}{
+		{
+			name:         "filter",
+			registration: noteRegistration,
+			dist:         &[]testNote{},
+			opt:          ListOption{Filter: &testNoteFilter{Title: "maintenance"}},
+			contains:     []string{"title = ?"},
+			excludes:     []string{"1 = 0"},
+		},
+		{
+			name:         "no filter",
+			registration: noteRegistration,
+			dist:         &[]testNote{},
+			opt:          ListOption{},
+			excludes:     []string{"title = ?"},
+		},
+		{
+			name:         "only deleted of a hard-deleted entity",
+			registration: noteRegistration,
+			dist:         &[]testNote{},
+			opt:          ListOption{EntityOption: model.EntityOption{OnlyDeleted: true}},
+			contains:     []string{"1 = 0"},
+		},
+		{
+			name:         "soft-deleted entity",
+			registration: trashNoteRegistration,
+			dist:         &[]testTrashNote{},
+			opt:          ListOption{Filter: &testNoteFilter{Title: "maintenance"}},
+			contains:     []string{"title = ?", "`deleted_at` IS NULL"},
+		},
+		{
+			name:         "only deleted of a soft-deleted entity",
+			registration: trashNoteRegistration,
+			dist:         &[]testTrashNote{},
+			opt:          ListOption{EntityOption: model.EntityOption{OnlyDeleted: true}},
+			contains:     []string{"deleted_at IS NOT NULL"},
+			excludes:     []string{"`deleted_at` IS NULL", "1 = 0"},
+		},
+		{
+			name:         "include deleted of a soft-deleted entity",
+			registration: trashNoteRegistration,
+			dist:         &[]testTrashNote{},
+			opt:          ListOption{EntityOption: model.EntityOption{IncludeDeleted: true}},
+			excludes:     []string{"deleted_at"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sql := listSQL(t, tc.registration, tc.dist, tc.opt)
+			for _, s := range tc.contains {
+				assert.Contains(t, sql, s)
+			}
+			for _, s := range tc.excludes {
+				assert.NotContains(t, sql, s)
+			}
+		})
+	}
+}
+
+func TestFromDTO(t *testing.T) {
+	note := noteRegistration.fromDTO(testNoteDTO{Title: " maintenance "})
+	assert.Equal(t, &testNote{Title: "maintenance"}, note)
+}
+
+// testUseCase records the entity written by the routes, and fails the other writes with err
+type testUseCase struct {
+	UseCase
+	created any
+	err     error
+}
+
+func (u *testUseCase) Create(c context.Context, entity eGorm.Entity, dist any) error {
+	u.created = dist
+	return nil
+}
+
+func (u *testUseCase) Update(c context.Context, entity eGorm.Entity, id uint, version *uint, dist any) error {
+	return u.err
+}
+
+func (u *testUseCase) Delete(c context.Context, entity eGorm.Entity, id uint, version *uint) error {
+	return u.err
+}
+
+func TestRoutes(t *testing.T) {
+	assert.Len(t, noteRegistration.Routes(&testUseCase{}), 5)
+	assert.Len(t, trashNoteRegistration.Routes(&testUseCase{}), 7)
+
+	gin.SetMode(gin.TestMode)
+	useCase := &testUseCase{}
+	engine := gin.New()
+	for _, route := range noteRegistration.Routes(useCase) {
+		engine.Handle(route.Method, route.Path, route.Handler)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/note", strings.NewReader(`{"title":" maintenance "}`))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, &testNote{Title: "maintenance"}, useCase.created)
+}
+
+func TestRoutesVersionConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useCase := &testUseCase{err: customerror.VersionConflict}
+	engine := gin.New()
+	for _, route := range noteRegistration.Routes(useCase) {
+		engine.Handle(route.Method, route.Path, route.Handler)
+	}
+
+	testCases := []struct {
+		name    string
+		method  string
+		ifMatch string
+		code    int
+	}{
+		{name: "update under If-Match", method: http.MethodPut, ifMatch: mGin.ETag(1), code: http.StatusPreconditionFailed},
+		{name: "update without If-Match", method: http.MethodPut, code: http.StatusConflict},
+		{name: "update under the wildcard", method: http.MethodPut, ifMatch: "*", code: http.StatusConflict},
+		{name: "delete under If-Match", method: http.MethodDelete, ifMatch: mGin.ETag(1), code: http.StatusPreconditionFailed},
+		{name: "delete without If-Match", method: http.MethodDelete, code: http.StatusConflict},
+		{name: "invalid If-Match", method: http.MethodPut, ifMatch: "1", code: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/note/1", strings.NewReader(`{"title":"maintenance"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
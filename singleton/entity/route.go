@@ -0,0 +1,252 @@
+package entity
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+	"github.com/a5932016/go-ddd-example/util/mGin"
+)
+
+// UseCase reads and writes the entities of the routes, given pointers to the entity types
+type UseCase interface {
+	Get(c context.Context, entity eGorm.Entity, id uint, dist any) error
+	List(c context.Context, entity eGorm.Entity, opt any, dist any) (total int64, err error)
+	Create(c context.Context, entity eGorm.Entity, dist any) error
+	Update(c context.Context, entity eGorm.Entity, id uint, version *uint, dist any) error
+	Delete(c context.Context, entity eGorm.Entity, id uint, version *uint) error
+	Restore(c context.Context, entity eGorm.Entity, id uint) error
+	Purge(c context.Context, entity eGorm.Entity, id uint) error
+}
+
+// Route is a route of the entity, guarded by the action on the entity's resource
+type Route struct {
+	Method   string
+	Path     string
+	Action   model.Action
+	RootOnly bool
+	Handler  gin.HandlerFunc
+}
+
+type bindIdURI struct {
+	ID uint `uri:"id" binding:"required,number"`
+}
+
+type listQuery struct {
+	IncludeDeleted bool `form:"includeDeleted"`
+	OnlyDeleted    bool `form:"onlyDeleted"`
+}
+
+// Routes returns the standard REST routes of the entity, with restore and purge of the trash for a soft-deleted entity
+func (r registration[T, O, D]) Routes(useCase UseCase) []Route {
+	path := "/" + r.def.Name
+	routes := []Route{
+		{Method: http.MethodGet, Path: path, Action: model.ActionRead, Handler: r.listHandler(useCase)},
+		{Method: http.MethodPost, Path: path, Action: model.ActionCreate, Handler: r.createHandler(useCase)},
+		{Method: http.MethodGet, Path: path + "/:id", Action: model.ActionRead, Handler: r.getHandler(useCase)},
+		{Method: http.MethodPut, Path: path + "/:id", Action: model.ActionUpdate, Handler: r.updateHandler(useCase)},
+		{Method: http.MethodDelete, Path: path + "/:id", Action: model.ActionDelete, Handler: r.deleteHandler(useCase)},
+	}
+	if r.SoftDelete() {
+		routes = append(routes,
+			Route{Method: http.MethodPut, Path: path + "/:id/restore", Action: model.ActionDelete, RootOnly: true, Handler: r.restoreHandler(useCase)},
+			Route{Method: http.MethodDelete, Path: path + "/:id/purge", Action: model.ActionDelete, RootOnly: true, Handler: r.purgeHandler(useCase)},
+		)
+	}
+	return routes
+}
+
+func (r registration[T, O, D]) getHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var boundIdURI bindIdURI
+		if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return
+		}
+
+		dist := new(T)
+		if err := useCase.Get(ctx, r.Entity(), boundIdURI.ID, dist); err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "useCase.Get")
+			return
+		}
+
+		ctx.WithData(dist).Response(http.StatusOK, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) listHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var query listQuery
+		if err := ctx.ShouldBindQuery(&query); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid Query")
+			return
+		}
+		filter := new(O)
+		if err := ctx.ShouldBindMQuery(filter); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid Query")
+			return
+		}
+
+		paginator := ctx.GetPaginator()
+		sort := ctx.GetSortBy(r.def.SortKeys)
+		opt := ListOption{
+			Filter: filter,
+			EntityOption: model.EntityOption{
+				SortBy:         sort,
+				IncludeDeleted: query.IncludeDeleted,
+				OnlyDeleted:    query.OnlyDeleted,
+				Offset:         &paginator.Offset,
+				Limit:          &paginator.Limit,
+			},
+		}
+
+		dist := []T{}
+		total, err := useCase.List(ctx, r.Entity(), opt, &dist)
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "useCase.List")
+			return
+		}
+
+		if sort != nil {
+			ctx.WithSort(ctx.GetSort())
+		}
+		paginator.SetTotalCount(int(total))
+		ctx.WithPaginator(paginator).WithData(dist).Response(http.StatusOK, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) createHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var body D
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		dist := r.fromDTO(body)
+		if err := useCase.Create(ctx, r.Entity(), dist); err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "useCase.Create")
+			return
+		}
+
+		ctx.WithData(dist).Response(http.StatusCreated, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) updateHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var boundIdURI bindIdURI
+		if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return
+		}
+		version, err := ctx.IfMatch()
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid If-Match")
+			return
+		}
+		var body D
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid JSON")
+			return
+		}
+
+		dist := r.fromDTO(body)
+		if err := useCase.Update(ctx, r.Entity(), boundIdURI.ID, version, dist); err != nil {
+			ctx.WithError(preconditionError(err, version)).Response(http.StatusInternalServerError, "useCase.Update")
+			return
+		}
+
+		ctx.WithData(dist).Response(http.StatusOK, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) deleteHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var boundIdURI bindIdURI
+		if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return
+		}
+		version, err := ctx.IfMatch()
+		if err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid If-Match")
+			return
+		}
+
+		if err := useCase.Delete(ctx, r.Entity(), boundIdURI.ID, version); err != nil {
+			ctx.WithError(preconditionError(err, version)).Response(http.StatusInternalServerError, "useCase.Delete")
+			return
+		}
+
+		ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) restoreHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var boundIdURI bindIdURI
+		if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return
+		}
+
+		if err := useCase.Restore(ctx, r.Entity(), boundIdURI.ID); err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "useCase.Restore")
+			return
+		}
+
+		ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+		return
+	}
+}
+
+func (r registration[T, O, D]) purgeHandler(useCase UseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := mGin.NewContext(c)
+
+		var boundIdURI bindIdURI
+		if err := ctx.ShouldBindUri(&boundIdURI); err != nil {
+			ctx.WithError(err).Response(http.StatusBadRequest, "Invalid URI")
+			return
+		}
+
+		if err := useCase.Purge(ctx, r.Entity(), boundIdURI.ID); err != nil {
+			ctx.WithError(err).Response(http.StatusInternalServerError, "useCase.Purge")
+			return
+		}
+
+		ctx.WithData(struct{}{}).Response(http.StatusOK, "")
+		return
+	}
+}
+
+// preconditionError reports the version conflict of a write under If-Match as its failed precondition
+func preconditionError(err error, version *uint) error {
+	if version != nil && errors.Is(err, customerror.VersionConflict) {
+		return customerror.VersionConflict.WithHTTPCode(http.StatusPreconditionFailed)
+	}
+	return err
+}
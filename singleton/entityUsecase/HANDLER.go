@@ -3,14 +3,22 @@ package entityUsecase
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
+	"github.com/a5932016/go-ddd-example/config"
 	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
 	"github.com/a5932016/go-ddd-example/repository"
 	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+	"github.com/a5932016/go-ddd-example/util/log"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+// trashRetentionInterval is how often the entities deleted longer than the retention are purged
+const trashRetentionInterval = time.Hour
+
 func NewEntityUseCase(dbRepo repository.DBRepository) EntityUseCase {
 	return EntityUseCase{
 		dbRepo: dbRepo,
@@ -21,54 +29,199 @@ type EntityUseCase struct {
 	dbRepo repository.DBRepository
 }
 
-func (h EntityUseCase) List(c context.Context, entity eGorm.Entity, opt any, dist any) (total int64, err error) {
-	tx := h.dbRepo.Begin()
-	defer tx.Rollback()
-	entityGORM := tx.EntityCtrl().Entity(entity)
-	if err = entityGORM.List(dist, opt); err != nil {
-		return 0, errors.Wrap(err, fmt.Sprintf("Entity(%s).List", entity.ModelName()))
+func (h EntityUseCase) Get(c context.Context, entity eGorm.Entity, id uint, dist any) error {
+	if err := h.dbRepo.EntityCtrl().WithContext(c).Entity(entity).Get(dist, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Get", entity.ModelName()))
 	}
-	if err = entityGORM.Count(&total, opt); err != nil {
-		return 0, errors.Wrap(err, fmt.Sprintf("Entity(%s).Count", entity.ModelName()))
+	return nil
+}
+
+func (h EntityUseCase) List(c context.Context, entity eGorm.Entity, opt any, dist any) (total int64, err error) {
+	// The page and the total are read in one transaction to match
+	err = h.dbRepo.Transaction(c, func(tx repository.DBRepository) error {
+		entityGORM := tx.EntityCtrl().Entity(entity)
+		if err := entityGORM.List(dist, opt); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).List", entity.ModelName()))
+		}
+		if err := entityGORM.Count(&total, opt); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).Count", entity.ModelName()))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
-	return
+	return total, nil
 }
 
 func (h EntityUseCase) Create(c context.Context, entity eGorm.Entity, dist any) error {
-	if err := h.dbRepo.EntityCtrl().Entity(entity).Create(dist); err != nil {
+	if err := h.dbRepo.EntityCtrl().WithContext(c).Entity(entity).Create(dist); err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return customerror.DuplicateName
 		}
 		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Create", entity.ModelName()))
 	}
+
+	var id interface{}
+	if e, ok := dist.(model.HasID); ok {
+		id = e.GetID()
+	}
+	h.audit(c, entity, "create", id, nil, dist)
 	return nil
 }
 
-func (h EntityUseCase) Update(c context.Context, entity eGorm.Entity, id uint, dist any) error {
-	tx := h.dbRepo.Begin()
-	defer tx.Rollback()
-	entityGORM := tx.EntityCtrl().Entity(entity)
-	if err := entityGORM.Update(dist, id); err != nil {
-		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			return customerror.DuplicateName
+// Update updates the entity of id to dist, only at the version when given.
+// A versioned entity fails with VersionConflict when it is changed by another request meanwhile.
+func (h EntityUseCase) Update(c context.Context, entity eGorm.Entity, id uint, version *uint, dist any) error {
+	// Read the entity before the update for the audit log, and for its version
+	before := reflect.New(reflect.TypeOf(dist).Elem()).Interface()
+
+	err := h.dbRepo.Transaction(c, func(tx repository.DBRepository) error {
+		entityGORM := tx.EntityCtrl().Entity(entity)
+		if err := entityGORM.Get(before, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return customerror.RecordNotFound
+			}
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).Get", entity.ModelName()))
+		}
+		if err := checkVersion(before, version); err != nil {
+			return err
+		}
+		if versioned, ok := dist.(model.Versioned); ok {
+			versioned.SetVersion(before.(model.Versioned).GetVersion())
+		}
+
+		if err := entityGORM.Update(dist, id); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return customerror.DuplicateName
+			}
+			if errors.Is(err, eGorm.ErrVersionConflict) {
+				return customerror.VersionConflict
+			}
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).Update", entity.ModelName()))
 		}
-		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Update", entity.ModelName()))
+		if err := entityGORM.Get(dist, id); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).Get", entity.ModelName()))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	if err := entityGORM.Get(dist, id); err != nil {
+
+	h.audit(c, entity, "update", id, before, dist)
+	return nil
+}
+
+// Delete deletes the entity of id, only at the version when given
+func (h EntityUseCase) Delete(c context.Context, entity eGorm.Entity, id uint, version *uint) error {
+	err := h.dbRepo.Transaction(c, func(tx repository.DBRepository) error {
+		entityGORM := tx.EntityCtrl().Entity(entity)
+		if version != nil {
+			current := reflect.New(reflect.TypeOf(entity)).Interface()
+			if err := entityGORM.Get(current, id); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return customerror.RecordNotFound
+				}
+				return errors.Wrap(err, fmt.Sprintf("Entity(%s).Get", entity.ModelName()))
+			}
+			if err := checkVersion(current, version); err != nil {
+				return err
+			}
+		}
+
+		if err := entityGORM.Delete(id, version); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Read at the version above, so changed meanwhile
+				if version != nil {
+					return customerror.VersionConflict
+				}
+				return customerror.RecordNotFound
+			}
+			return errors.Wrap(err, fmt.Sprintf("Entity(%s).Delete", entity.ModelName()))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	h.audit(c, entity, "delete", id, nil, nil)
+	return nil
+}
+
+// Restore restores the soft-deleted entity of id from the trash
+func (h EntityUseCase) Restore(c context.Context, entity eGorm.Entity, id uint) error {
+	if err := h.dbRepo.EntityCtrl().WithContext(c).Entity(entity).Restore(id); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return customerror.RecordNotFound
 		}
-		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Get", entity.ModelName()))
+		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Restore", entity.ModelName()))
 	}
-	if err := tx.Commit(); err != nil {
-		return errors.Wrap(err, "commit")
+
+	h.audit(c, entity, "restore", id, nil, nil)
+	return nil
+}
+
+// Purge permanently deletes the entity of id, whether in the trash or not
+func (h EntityUseCase) Purge(c context.Context, entity eGorm.Entity, id uint) error {
+	if err := h.dbRepo.EntityCtrl().WithContext(c).Entity(entity).Purge(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Purge", entity.ModelName()))
 	}
+
+	h.audit(c, entity, "purge", id, nil, nil)
 	return nil
 }
 
-func (h EntityUseCase) Delete(c context.Context, entity eGorm.Entity, id uint) error {
-	if err := h.dbRepo.EntityCtrl().Entity(entity).Delete(id); err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Entity(%s).Delete", entity.ModelName()))
+// TrashRetention purges the entities soft-deleted longer than the retention until c is done,
+// keeping them forever without a retention
+func (h EntityUseCase) TrashRetention(c context.Context, entities []eGorm.Entity) {
+	retention := time.Duration(config.Env.Entity.TrashRetentionDays) * 24 * time.Hour
+	if retention <= 0 || len(entities) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(trashRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().Add(-retention)
+		for _, entity := range entities {
+			purged, err := h.dbRepo.EntityCtrl().WithContext(c).Entity(entity).PurgeDeleted(before)
+			if err != nil {
+				log.WithError(err).Errorf("Entity(%s).PurgeDeleted", entity.ModelName())
+			} else if purged > 0 {
+				log.Infof("purged %d %s deleted over %d days ago", purged, entity.ModelName(), config.Env.Entity.TrashRetentionDays)
+			}
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkVersion checks the current entity is at the version when given, which an unversioned entity never is
+func checkVersion(current any, version *uint) error {
+	if version == nil {
+		return nil
+	}
+	if versioned, ok := current.(model.Versioned); !ok || versioned.GetVersion() != *version {
+		return customerror.VersionConflict
 	}
 	return nil
 }
+
+// audit records the action on the entity, named <model name>.<verb>, with the changes from before to after
+func (h EntityUseCase) audit(c context.Context, entity eGorm.Entity, verb string, id, before, after any) {
+	entry := repository.NewAuditLog(c, entity.ModelName()+"."+verb, model.Resource(entity.ModelName()), id)
+	repository.WriteAuditLog(c, h.dbRepo, entry, before, after)
+}
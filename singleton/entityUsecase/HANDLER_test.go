@@ -0,0 +1,136 @@
+package entityUsecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository/mysql"
+	"github.com/a5932016/go-ddd-example/singleton/entity"
+	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
+)
+
+type testAnnouncementDTO struct {
+	Title string
+}
+
+func (d testAnnouncementDTO) ToEntity() model.Announcement {
+	return model.Announcement{Title: d.Title}
+}
+
+// newTestEntityUseCase returns the use case of the announcements of an in-memory database
+func newTestEntityUseCase(t *testing.T) (EntityUseCase, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// Every connection opens another in-memory database, so keep the one with the tables
+	sqlDB, err := db.DB()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if !assert.NoError(t, db.AutoMigrate(&model.Announcement{}, &model.AuditLog{})) {
+		t.FailNow()
+	}
+	entityCtrl := entity.NewEntityHandler(db, []entity.Registration{
+		entity.Register(entity.Definition[model.Announcement, model.AnnouncementFilter, testAnnouncementDTO]{
+			Name:      "announcement",
+			Resource:  model.ResourceAnnouncement,
+			ListQuery: mysql.AnnouncementListQuery,
+		}),
+	})
+	return NewEntityUseCase(mysql.NewDBRepository(db, entityCtrl)), db
+}
+
+func TestUpdateVersion(t *testing.T) {
+	h, _ := newTestEntityUseCase(t)
+	ctx := context.Background()
+
+	announcement := model.Announcement{Title: "created"}
+	assert.NoError(t, h.Create(ctx, model.Announcement{}, &announcement))
+
+	// Two writers send If-Match of the same version
+	version := announcement.Version
+	first := model.Announcement{Title: "first"}
+	assert.NoError(t, h.Update(ctx, model.Announcement{}, announcement.ID, &version, &first))
+	assert.EqualValues(t, 2, first.Version)
+
+	second := model.Announcement{Title: "second"}
+	assert.ErrorIs(t, h.Update(ctx, model.Announcement{}, announcement.ID, &version, &second), customerror.VersionConflict)
+	assert.ErrorIs(t, h.Delete(ctx, model.Announcement{}, announcement.ID, &version), customerror.VersionConflict)
+
+	var current model.Announcement
+	assert.NoError(t, h.Get(ctx, model.Announcement{}, announcement.ID, &current))
+	assert.Equal(t, "first", current.Title)
+	assert.EqualValues(t, 2, current.Version)
+
+	// Without If-Match the write is made at the current version
+	last := model.Announcement{Title: "last"}
+	assert.NoError(t, h.Update(ctx, model.Announcement{}, announcement.ID, nil, &last))
+	assert.EqualValues(t, 3, last.Version)
+
+	version = last.Version
+	assert.NoError(t, h.Delete(ctx, model.Announcement{}, announcement.ID, &version))
+	assert.ErrorIs(t, h.Update(ctx, model.Announcement{}, announcement.ID, nil, &last), customerror.RecordNotFound)
+}
+
+func TestCheckVersion(t *testing.T) {
+	version := uint(2)
+	stale := uint(1)
+
+	assert.NoError(t, checkVersion(&model.Announcement{Version: 2}, nil))
+	assert.NoError(t, checkVersion(&model.Announcement{Version: 2}, &version))
+	assert.ErrorIs(t, checkVersion(&model.Announcement{Version: 2}, &stale), customerror.VersionConflict)
+	assert.NoError(t, checkVersion(&model.User{}, nil))
+	assert.ErrorIs(t, checkVersion(&model.User{}, &version), customerror.VersionConflict, "an unversioned entity has no version")
+}
+
+func TestTrashRetention(t *testing.T) {
+	h, db := newTestEntityUseCase(t)
+	ctx := context.Background()
+	defer func(days int) { config.Env.Entity.TrashRetentionDays = days }(config.Env.Entity.TrashRetentionDays)
+
+	var announcements []model.Announcement
+	for _, deletedAt := range []time.Time{{}, time.Now().Add(-48 * time.Hour), time.Now().Add(-time.Hour)} {
+		announcement := model.Announcement{Title: "announcement"}
+		assert.NoError(t, h.Create(ctx, model.Announcement{}, &announcement))
+		if !deletedAt.IsZero() {
+			assert.NoError(t, db.Model(&announcement).Update("deleted_at", deletedAt).Error)
+		}
+		announcements = append(announcements, announcement)
+	}
+	remaining := func() (ids []uint) {
+		assert.NoError(t, db.Unscoped().Model(&model.Announcement{}).Order("id").Pluck("id", &ids).Error)
+		return ids
+	}
+
+	// Without a retention the trash is kept, and it returns at once
+	config.Env.Entity.TrashRetentionDays = 0
+	h.TrashRetention(ctx, []eGorm.Entity{model.Announcement{}})
+	assert.Len(t, remaining(), 3)
+
+	// The entities deleted longer than the retention are purged until canceled
+	config.Env.Entity.TrashRetentionDays = 1
+	retentionCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.TrashRetention(retentionCtx, []eGorm.Entity{model.Announcement{}})
+	}()
+	assert.Eventually(t, func() bool { return len(remaining()) == 2 }, time.Second, 10*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TrashRetention did not stop")
+	}
+	assert.Equal(t, []uint{announcements[0].ID, announcements[2].ID}, remaining())
+}
@@ -1,7 +1,9 @@
 package session
 
 import (
+	"context"
 	"net/url"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -9,17 +11,25 @@ import (
 
 // Session represents a user session
 type Session interface {
-	Set(key, value interface{}) error // set session value
-	Get(key interface{}) interface{}  // get session value
-	Delete(key interface{}) error     // delete session value
-	SessionID() string                // get current session ID
+	Set(ctx context.Context, key, value interface{}) error    // set session value
+	Get(ctx context.Context, key interface{}) interface{}     // get session value
+	Delete(ctx context.Context, key interface{}) error        // delete session value
+	Incr(ctx context.Context, key interface{}) (int64, error) // atomically increment session value
+	SessionID() string                                        // get current session ID
 }
 
 type Provider interface {
-	SessionInit(sid string) (Session, error)
-	SessionRead(sid string) (Session, error)
-	SessionDestroy(sid string) error
-	SessionGC(maxLifeTime MaxLifeTime)
+	SessionInit(ctx context.Context, sid string) (Session, error)
+	SessionRead(ctx context.Context, sid string) (Session, error)
+	SessionPeek(ctx context.Context, sid string) (Session, error) // read without refreshing the expiration
+	SessionDestroy(ctx context.Context, sid string) error
+	SessionGC(ctx context.Context, maxLifeTime MaxLifeTime)
+
+	// Owner index: the session IDs of an owner, oldest first
+	SessionBindOwner(ctx context.Context, owner, sid string, createdAt time.Time) error
+	SessionUnbindOwner(ctx context.Context, owner string, sids ...string) error
+	SessionOwnerList(ctx context.Context, owner string) ([]string, error)
+	SessionTouchOwner(ctx context.Context, owner string) error // keep the index alive as long as the owner's sessions
 }
 
 var (
@@ -28,12 +38,14 @@ var (
 
 type SessionName string
 type MaxLifeTime int64
+type MaxSessions int // concurrent sessions per owner, 0 for unlimited
 
-func NewManager(provider Provider, sessionName SessionName, maxLifeTime MaxLifeTime) *Manager {
+func NewManager(provider Provider, sessionName SessionName, maxLifeTime MaxLifeTime, maxSessions MaxSessions) *Manager {
 	return &Manager{
 		provider:    provider,
 		sessionName: sessionName,
 		maxLifeTime: maxLifeTime,
+		maxSessions: maxSessions,
 	}
 }
 
@@ -41,6 +53,15 @@ type Manager struct {
 	provider    Provider
 	sessionName SessionName
 	maxLifeTime MaxLifeTime
+	maxSessions MaxSessions
+}
+
+func (manager *Manager) SessionName() string {
+	return string(manager.sessionName)
+}
+
+func (manager *Manager) MaxLifeTime() MaxLifeTime {
+	return manager.maxLifeTime
 }
 
 func (manager *Manager) newSessionID() string {
@@ -53,11 +74,11 @@ type SessionCarrier struct {
 	Session Session
 }
 
-func (manager *Manager) SessionStart(unescapedID string) (SessionCarrier, error) {
+func (manager *Manager) SessionStart(ctx context.Context, unescapedID string) (SessionCarrier, error) {
 	// New session
 	if unescapedID == "" {
 		sid := manager.newSessionID()
-		session, err := manager.provider.SessionInit(sid)
+		session, err := manager.provider.SessionInit(ctx, sid)
 		if err != nil {
 			return SessionCarrier{}, errors.WithMessagef(err,
 				"(sessionName: %s) provider.SessionInit(%s)", manager.sessionName, sid)
@@ -75,7 +96,7 @@ func (manager *Manager) SessionStart(unescapedID string) (SessionCarrier, error)
 		return SessionCarrier{}, errors.WithMessagef(err,
 			"(SessionName: %s) url.QueryUnescape(%s)", manager.sessionName, unescapedID)
 	}
-	session, err := manager.provider.SessionRead(sid)
+	session, err := manager.provider.SessionRead(ctx, sid)
 	if err != nil {
 		if errors.Is(err, ErrSessionNotExisted) {
 			return SessionCarrier{}, err
@@ -83,6 +104,19 @@ func (manager *Manager) SessionStart(unescapedID string) (SessionCarrier, error)
 		return SessionCarrier{}, errors.WithMessagef(err,
 			"(SessionName: %s) provider.SessionRead(%s)", manager.sessionName, sid)
 	}
+	if isPreAuth(ctx, session) {
+		return SessionCarrier{}, ErrSessionNotExisted
+	}
+	if err := session.Set(ctx, keyLastSeenAt, formatTime(time.Now())); err != nil {
+		return SessionCarrier{}, errors.WithMessagef(err,
+			"(SessionName: %s) Session.Set(%s)", manager.sessionName, keyLastSeenAt)
+	}
+	if owner := getString(ctx, session, keyOwner); owner != "" {
+		if err := manager.provider.SessionTouchOwner(ctx, owner); err != nil {
+			return SessionCarrier{}, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionTouchOwner(%s)", manager.sessionName, owner)
+		}
+	}
 	return SessionCarrier{
 		Name:    string(manager.sessionName),
 		ID:      unescapedID,
@@ -90,24 +124,83 @@ func (manager *Manager) SessionStart(unescapedID string) (SessionCarrier, error)
 	}, nil
 }
 
-func (manager *Manager) SessionDestroy(unescapedID string) error {
+// SessionPeek reads an existing session without refreshing it
+func (manager *Manager) SessionPeek(ctx context.Context, unescapedID string) (SessionCarrier, error) {
+	sid, err := url.QueryUnescape(unescapedID)
+	if err != nil {
+		return SessionCarrier{}, errors.WithMessagef(err,
+			"(SessionName: %s) url.QueryUnescape(%s)", manager.sessionName, unescapedID)
+	}
+	session, err := manager.provider.SessionPeek(ctx, sid)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotExisted) {
+			return SessionCarrier{}, err
+		}
+		return SessionCarrier{}, errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionPeek(%s)", manager.sessionName, sid)
+	}
+	return SessionCarrier{
+		Name:    string(manager.sessionName),
+		ID:      unescapedID,
+		Session: session,
+	}, nil
+}
+
+func (manager *Manager) SessionDestroy(ctx context.Context, unescapedID string) error {
 	if unescapedID != "" {
 		sid, err := url.QueryUnescape(unescapedID)
 		if err != nil {
 			return errors.WithMessagef(err,
 				"(SessionName: %s) url.QueryUnescape(%s)", manager.sessionName, unescapedID)
 		}
-		if err := manager.provider.SessionDestroy(sid); err != nil {
-			return errors.WithMessagef(err,
-				"(SessionName: %s) provider.SessionDestroy(%s)", manager.sessionName, sid)
+		return manager.destroy(ctx, sid)
+	}
+	return nil
+}
+
+// destroy removes the session and its entry in the owner index
+func (manager *Manager) destroy(ctx context.Context, sid string) error {
+	session, err := manager.provider.SessionPeek(ctx, sid)
+	if err != nil && !errors.Is(err, ErrSessionNotExisted) {
+		return errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionPeek(%s)", manager.sessionName, sid)
+	}
+	if session != nil {
+		if owner := getString(ctx, session, keyOwner); owner != "" {
+			if err := manager.provider.SessionUnbindOwner(ctx, owner, sid); err != nil {
+				return errors.WithMessagef(err,
+					"(SessionName: %s) provider.SessionUnbindOwner(%s)", manager.sessionName, owner)
+			}
 		}
 	}
+
+	if err := manager.provider.SessionDestroy(ctx, sid); err != nil {
+		return errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionDestroy(%s)", manager.sessionName, sid)
+	}
 	return nil
 }
 
-// func (manager *Manager) GC() {
-// 	manager.provider.SessionGC(manager.maxLifeTime)
-// 	time.AfterFunc(time.Duration(manager.maxLifeTime)*time.Second, func() {
-// 		manager.GC()
-// 	})
-// }
+// maxGCInterval bounds how long an expired session may stay in a provider without its own expiration
+const maxGCInterval = time.Minute
+
+// GC removes the expired sessions periodically until ctx is done
+func (manager *Manager) GC(ctx context.Context) {
+	interval := time.Duration(manager.maxLifeTime) * time.Second
+	if interval <= 0 || interval > maxGCInterval {
+		interval = maxGCInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		manager.provider.SessionGC(ctx, manager.maxLifeTime)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/util/locking"
+	"github.com/pkg/errors"
+)
+
+// Session fields kept by the manager
+const (
+	keyOwner      = "_owner"
+	keyIP         = "_ip"
+	keyUserAgent  = "_userAgent"
+	keyCreatedAt  = "_createdAt"
+	keyLastSeenAt = "_lastSeenAt"
+)
+
+// ClientInfo describes the client starting a session
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionInfo describes an active session of an owner
+type SessionInfo struct {
+	// Handle identifies the session without exposing the session ID
+	Handle     string    `json:"handle"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+// SessionHandle returns the public handle of the session ID
+func SessionHandle(unescapedID string) string {
+	sid, err := url.QueryUnescape(unescapedID)
+	if err != nil {
+		sid = unescapedID
+	}
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SessionStartForOwner starts a new session indexed under owner, evicting the owner's oldest sessions over the limit
+func (manager *Manager) SessionStartForOwner(ctx context.Context, owner string, client ClientInfo) (SessionCarrier, error) {
+	sc, err := manager.SessionStart(ctx, "")
+	if err != nil {
+		return SessionCarrier{}, err
+	}
+
+	now := time.Now()
+	fields := [][2]string{
+		{keyOwner, owner},
+		{keyIP, client.IP},
+		{keyUserAgent, client.UserAgent},
+		{keyCreatedAt, formatTime(now)},
+		{keyLastSeenAt, formatTime(now)},
+	}
+	for _, field := range fields {
+		if err := sc.Session.Set(ctx, field[0], field[1]); err != nil {
+			return SessionCarrier{}, errors.WithMessagef(err,
+				"(SessionName: %s) Session.Set(%s)", manager.sessionName, field[0])
+		}
+	}
+
+	defer locking.Lock(ctx, manager.ownerLockName(owner))()
+
+	if err := manager.provider.SessionBindOwner(ctx, owner, sc.Session.SessionID(), now); err != nil {
+		return SessionCarrier{}, errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionBindOwner(%s)", manager.sessionName, owner)
+	}
+
+	if manager.maxSessions > 0 {
+		sids, err := manager.aliveOwnerSessions(ctx, owner)
+		if err != nil {
+			return SessionCarrier{}, err
+		}
+		for i := 0; i < len(sids)-int(manager.maxSessions); i++ {
+			if err := manager.destroy(ctx, sids[i]); err != nil {
+				return SessionCarrier{}, err
+			}
+		}
+	}
+
+	return sc, nil
+}
+
+// ListOwnerSessions returns the owner's active sessions, oldest first
+func (manager *Manager) ListOwnerSessions(ctx context.Context, owner string) ([]SessionInfo, error) {
+	sids, err := manager.aliveOwnerSessions(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(sids))
+	for _, sid := range sids {
+		session, err := manager.provider.SessionPeek(ctx, sid)
+		if err != nil {
+			if errors.Is(err, ErrSessionNotExisted) {
+				continue
+			}
+			return nil, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionPeek(%s)", manager.sessionName, sid)
+		}
+		infos = append(infos, SessionInfo{
+			Handle:     SessionHandle(sid),
+			IP:         getString(ctx, session, keyIP),
+			UserAgent:  getString(ctx, session, keyUserAgent),
+			CreatedAt:  parseTime(getString(ctx, session, keyCreatedAt)),
+			LastSeenAt: parseTime(getString(ctx, session, keyLastSeenAt)),
+		})
+	}
+
+	return infos, nil
+}
+
+// RevokeOwnerSession destroys the owner's session with the handle
+func (manager *Manager) RevokeOwnerSession(ctx context.Context, owner, handle string) error {
+	sids, err := manager.aliveOwnerSessions(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	for _, sid := range sids {
+		if SessionHandle(sid) == handle {
+			return manager.destroy(ctx, sid)
+		}
+	}
+
+	return ErrSessionNotExisted
+}
+
+// RevokeOwnerSessions destroys every session of the owner except the given one, and returns how many were destroyed
+func (manager *Manager) RevokeOwnerSessions(ctx context.Context, owner, exceptUnescapedID string) (int, error) {
+	exceptSID, err := url.QueryUnescape(exceptUnescapedID)
+	if err != nil {
+		return 0, errors.WithMessagef(err,
+			"(SessionName: %s) url.QueryUnescape(%s)", manager.sessionName, exceptUnescapedID)
+	}
+
+	sids, err := manager.provider.SessionOwnerList(ctx, owner)
+	if err != nil {
+		return 0, errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionOwnerList(%s)", manager.sessionName, owner)
+	}
+
+	revoked := 0
+	for _, sid := range sids {
+		if sid == exceptSID {
+			continue
+		}
+		if err := manager.provider.SessionUnbindOwner(ctx, owner, sid); err != nil {
+			return revoked, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionUnbindOwner(%s)", manager.sessionName, owner)
+		}
+		if err := manager.provider.SessionDestroy(ctx, sid); err != nil {
+			return revoked, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionDestroy(%s)", manager.sessionName, sid)
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// aliveOwnerSessions returns the owner's session IDs, oldest first, dropping the expired ones from the index
+func (manager *Manager) aliveOwnerSessions(ctx context.Context, owner string) ([]string, error) {
+	sids, err := manager.provider.SessionOwnerList(ctx, owner)
+	if err != nil {
+		return nil, errors.WithMessagef(err,
+			"(SessionName: %s) provider.SessionOwnerList(%s)", manager.sessionName, owner)
+	}
+
+	var alive, expired []string
+	for _, sid := range sids {
+		if _, err := manager.provider.SessionPeek(ctx, sid); err != nil {
+			if errors.Is(err, ErrSessionNotExisted) {
+				expired = append(expired, sid)
+				continue
+			}
+			return nil, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionPeek(%s)", manager.sessionName, sid)
+		}
+		alive = append(alive, sid)
+	}
+
+	if len(expired) > 0 {
+		if err := manager.provider.SessionUnbindOwner(ctx, owner, expired...); err != nil {
+			return nil, errors.WithMessagef(err,
+				"(SessionName: %s) provider.SessionUnbindOwner(%s)", manager.sessionName, owner)
+		}
+	}
+
+	return alive, nil
+}
+
+func (manager *Manager) ownerLockName(owner string) string {
+	return fmt.Sprintf("%s_owner:%s", manager.sessionName, owner)
+}
+
+func getString(ctx context.Context, session Session, key string) string {
+	if v, ok := session.Get(ctx, key).(string); ok {
+		return v
+	}
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func parseTime(s string) time.Time {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
@@ -0,0 +1,100 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/singleton/session/provider/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerSessions(t *testing.T) {
+	ctx := context.Background()
+	manager := session.NewManager(memory.NewMemoryProvider(3600), "session", 3600, 2)
+	client := session.ClientInfo{IP: "127.0.0.1", UserAgent: "test"}
+
+	first, err := manager.SessionStartForOwner(ctx, "user:1", client)
+	assert.NoError(t, err)
+	// Keep the creation order distinct
+	time.Sleep(time.Millisecond)
+	second, err := manager.SessionStartForOwner(ctx, "user:1", client)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	third, err := manager.SessionStartForOwner(ctx, "user:1", client)
+	assert.NoError(t, err)
+
+	// The oldest session is evicted over the limit
+	_, err = manager.SessionStart(ctx, first.ID)
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+
+	sessions, err := manager.ListOwnerSessions(ctx, "user:1")
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, session.SessionHandle(second.ID), sessions[0].Handle)
+		assert.Equal(t, "127.0.0.1", sessions[0].IP)
+		assert.Equal(t, "test", sessions[0].UserAgent)
+		assert.False(t, sessions[0].CreatedAt.IsZero())
+	}
+
+	assert.NoError(t, manager.RevokeOwnerSession(ctx, "user:1", session.SessionHandle(second.ID)))
+	assert.ErrorIs(t, manager.RevokeOwnerSession(ctx, "user:1", session.SessionHandle(second.ID)), session.ErrSessionNotExisted)
+
+	revoked, err := manager.RevokeOwnerSessions(ctx, "user:1", third.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, revoked)
+	revoked, err = manager.RevokeOwnerSessions(ctx, "user:1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+
+	sessions, err = manager.ListOwnerSessions(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestManagerGCStops(t *testing.T) {
+	manager := session.NewManager(memory.NewMemoryProvider(1), "session", 1, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.GC(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GC did not stop")
+	}
+}
+
+// touchProvider records the owners whose index was kept alive
+type touchProvider struct {
+	*memory.MemoryProvider
+	touched []string
+}
+
+func (p *touchProvider) SessionTouchOwner(ctx context.Context, owner string) error {
+	p.touched = append(p.touched, owner)
+	return p.MemoryProvider.SessionTouchOwner(ctx, owner)
+}
+
+func TestOwnerIndexTouchedOnRead(t *testing.T) {
+	ctx := context.Background()
+	provider := &touchProvider{MemoryProvider: memory.NewMemoryProvider(3600)}
+	manager := session.NewManager(provider, "session", 3600, 0)
+
+	owned, err := manager.SessionStartForOwner(ctx, "user:1", session.ClientInfo{})
+	assert.NoError(t, err)
+	anonymous, err := manager.SessionStart(ctx, "")
+	assert.NoError(t, err)
+
+	_, err = manager.SessionStart(ctx, owned.ID)
+	assert.NoError(t, err)
+	_, err = manager.SessionStart(ctx, anonymous.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user:1"}, provider.touched)
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Pre-auth session fields kept by the manager
+const (
+	keyPreAuthExpiresAt = "_preAuthExpiresAt"
+	keyPreAuthAttempts  = "_preAuthAttempts"
+)
+
+// SessionStartPreAuth starts a short-lived session for a login waiting for its second factor.
+// It is rejected by SessionStart, so it can not be used as an authenticated session.
+func (manager *Manager) SessionStartPreAuth(ctx context.Context, lifeTime time.Duration) (SessionCarrier, error) {
+	sc, err := manager.SessionStart(ctx, "")
+	if err != nil {
+		return SessionCarrier{}, err
+	}
+
+	if err := sc.Session.Set(ctx, keyPreAuthExpiresAt, formatTime(time.Now().Add(lifeTime))); err != nil {
+		return SessionCarrier{}, errors.WithMessagef(err,
+			"(SessionName: %s) Session.Set(%s)", manager.sessionName, keyPreAuthExpiresAt)
+	}
+
+	return sc, nil
+}
+
+// SessionReadPreAuth reads an unexpired pre-auth session without refreshing it
+func (manager *Manager) SessionReadPreAuth(ctx context.Context, unescapedID string) (SessionCarrier, error) {
+	sc, err := manager.SessionPeek(ctx, unescapedID)
+	if err != nil {
+		return SessionCarrier{}, err
+	}
+
+	expiresAt := getString(ctx, sc.Session, keyPreAuthExpiresAt)
+	if expiresAt == "" {
+		return SessionCarrier{}, ErrSessionNotExisted
+	}
+	if !time.Now().Before(parseTime(expiresAt)) {
+		if err := manager.SessionDestroy(ctx, unescapedID); err != nil {
+			return SessionCarrier{}, err
+		}
+		return SessionCarrier{}, ErrSessionNotExisted
+	}
+
+	return sc, nil
+}
+
+// CountPreAuthAttempt counts a second factor attempt on the pre-auth session and returns the count.
+// The count is incremented atomically, so concurrent attempts each get their own count.
+func (manager *Manager) CountPreAuthAttempt(ctx context.Context, sc SessionCarrier) (int, error) {
+	attempts, err := sc.Session.Incr(ctx, keyPreAuthAttempts)
+	if err != nil {
+		return 0, errors.WithMessagef(err,
+			"(SessionName: %s) Session.Incr(%s)", manager.sessionName, keyPreAuthAttempts)
+	}
+	return int(attempts), nil
+}
+
+func isPreAuth(ctx context.Context, session Session) bool {
+	return getString(ctx, session, keyPreAuthExpiresAt) != ""
+}
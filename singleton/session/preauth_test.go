@@ -0,0 +1,81 @@
+package session_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/singleton/session/provider/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreAuthSession(t *testing.T) {
+	ctx := context.Background()
+	manager := session.NewManager(memory.NewMemoryProvider(3600), "session", 3600, 0)
+
+	sc, err := manager.SessionStartPreAuth(ctx, time.Minute)
+	assert.NoError(t, err)
+
+	// A pre-auth session is not an authenticated session
+	_, err = manager.SessionStart(ctx, sc.ID)
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+
+	read, err := manager.SessionReadPreAuth(ctx, sc.ID)
+	assert.NoError(t, err)
+	attempts, err := manager.CountPreAuthAttempt(ctx, read)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	attempts, err = manager.CountPreAuthAttempt(ctx, read)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// An authenticated session is not a pre-auth session
+	full, err := manager.SessionStart(ctx, "")
+	assert.NoError(t, err)
+	_, err = manager.SessionReadPreAuth(ctx, full.ID)
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+
+	// An expired pre-auth session is destroyed
+	expired, err := manager.SessionStartPreAuth(ctx, -time.Second)
+	assert.NoError(t, err)
+	_, err = manager.SessionReadPreAuth(ctx, expired.ID)
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+	_, err = manager.SessionPeek(ctx, expired.ID)
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+}
+
+func TestCountPreAuthAttemptConcurrent(t *testing.T) {
+	ctx := context.Background()
+	manager := session.NewManager(memory.NewMemoryProvider(3600), "session", 3600, 0)
+
+	sc, err := manager.SessionStartPreAuth(ctx, time.Minute)
+	assert.NoError(t, err)
+
+	// Every concurrent attempt gets its own count, none of them reads a stale one
+	const concurrent = 50
+	counts := make([]int, concurrent)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			read, err := manager.SessionReadPreAuth(ctx, sc.ID)
+			if assert.NoError(t, err) {
+				counts[i], err = manager.CountPreAuthAttempt(ctx, read)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[int]bool, concurrent)
+	for _, count := range counts {
+		seen[count] = true
+	}
+	assert.Len(t, seen, concurrent)
+	for i := 1; i <= concurrent; i++ {
+		assert.True(t, seen[i], "count %d", i)
+	}
+}
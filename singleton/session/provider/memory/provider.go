@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/singleton/session"
+)
+
+// NewMemoryProvider keeps the sessions in the process memory, so they are lost on restart and not shared between instances
+func NewMemoryProvider(maxLifeTime session.MaxLifeTime) *MemoryProvider {
+	return &MemoryProvider{
+		sessions:    make(map[string]*MemorySession),
+		owners:      make(map[string]map[string]time.Time),
+		maxLifeTime: maxLifeTime,
+		now:         time.Now,
+	}
+}
+
+type MemoryProvider struct {
+	lock        sync.Mutex
+	sessions    map[string]*MemorySession
+	owners      map[string]map[string]time.Time
+	maxLifeTime session.MaxLifeTime
+	now         func() time.Time
+}
+
+func (mp *MemoryProvider) expiration() time.Time {
+	return mp.now().Add(time.Duration(mp.maxLifeTime) * time.Second)
+}
+
+// get returns the unexpired session, the caller must hold the lock
+func (mp *MemoryProvider) get(sid string) (*MemorySession, bool) {
+	s, ok := mp.sessions[sid]
+	if !ok {
+		return nil, false
+	}
+	if !mp.now().Before(s.expiresAt) {
+		delete(mp.sessions, sid)
+		return nil, false
+	}
+	return s, true
+}
+
+func (mp *MemoryProvider) SessionInit(ctx context.Context, sid string) (session.Session, error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	s := &MemorySession{
+		sid:       sid,
+		values:    map[string]string{"init": "1"},
+		expiresAt: mp.expiration(),
+	}
+	mp.sessions[sid] = s
+
+	return s, nil
+}
+
+func (mp *MemoryProvider) SessionRead(ctx context.Context, sid string) (session.Session, error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	s, ok := mp.get(sid)
+	if !ok {
+		return nil, session.ErrSessionNotExisted
+	}
+
+	// Refresh the session expiration time
+	s.expiresAt = mp.expiration()
+
+	return s, nil
+}
+
+func (mp *MemoryProvider) SessionPeek(ctx context.Context, sid string) (session.Session, error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	s, ok := mp.get(sid)
+	if !ok {
+		return nil, session.ErrSessionNotExisted
+	}
+
+	return s, nil
+}
+
+func (mp *MemoryProvider) SessionDestroy(ctx context.Context, sid string) error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	delete(mp.sessions, sid)
+	return nil
+}
+
+// SessionGC removes the expired sessions and their owner index entries
+func (mp *MemoryProvider) SessionGC(ctx context.Context, maxLifeTime session.MaxLifeTime) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	for sid := range mp.sessions {
+		mp.get(sid)
+	}
+	for owner, sids := range mp.owners {
+		for sid := range sids {
+			if _, ok := mp.sessions[sid]; !ok {
+				delete(sids, sid)
+			}
+		}
+		if len(sids) == 0 {
+			delete(mp.owners, owner)
+		}
+	}
+}
+
+func (mp *MemoryProvider) SessionBindOwner(ctx context.Context, owner, sid string, createdAt time.Time) error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	if mp.owners[owner] == nil {
+		mp.owners[owner] = make(map[string]time.Time)
+	}
+	mp.owners[owner][sid] = createdAt
+
+	return nil
+}
+
+func (mp *MemoryProvider) SessionUnbindOwner(ctx context.Context, owner string, sids ...string) error {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	for _, sid := range sids {
+		delete(mp.owners[owner], sid)
+	}
+	if len(mp.owners[owner]) == 0 {
+		delete(mp.owners, owner)
+	}
+
+	return nil
+}
+
+// SessionTouchOwner does nothing, the owner index is pruned by SessionGC
+func (mp *MemoryProvider) SessionTouchOwner(ctx context.Context, owner string) error {
+	return nil
+}
+
+func (mp *MemoryProvider) SessionOwnerList(ctx context.Context, owner string) ([]string, error) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	sids := make([]string, 0, len(mp.owners[owner]))
+	for sid := range mp.owners[owner] {
+		sids = append(sids, sid)
+	}
+	sort.Slice(sids, func(i, j int) bool {
+		ti, tj := mp.owners[owner][sids[i]], mp.owners[owner][sids[j]]
+		if ti.Equal(tj) {
+			return sids[i] < sids[j]
+		}
+		return ti.Before(tj)
+	})
+
+	return sids, nil
+}
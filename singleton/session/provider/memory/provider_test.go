@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryProviderExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	mp := NewMemoryProvider(60)
+	mp.now = func() time.Time { return now }
+
+	s, err := mp.SessionInit(ctx, "a")
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set(ctx, "user", 1))
+	assert.Equal(t, "1", s.Get(ctx, "user"))
+	assert.Equal(t, "", s.Get(ctx, "missing"))
+
+	// A missing value increments from 0, a non-integer value is refused
+	count, err := s.Incr(ctx, "count")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	count, err = s.Incr(ctx, "user")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.NoError(t, s.Set(ctx, "name", "alice"))
+	_, err = s.Incr(ctx, "name")
+	assert.Error(t, err)
+
+	// Reading refreshes the expiration, peeking does not
+	now = now.Add(50 * time.Second)
+	_, err = mp.SessionRead(ctx, "a")
+	assert.NoError(t, err)
+	now = now.Add(50 * time.Second)
+	_, err = mp.SessionPeek(ctx, "a")
+	assert.NoError(t, err)
+
+	now = now.Add(10 * time.Second)
+	_, err = mp.SessionPeek(ctx, "a")
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+	_, err = mp.SessionRead(ctx, "a")
+	assert.ErrorIs(t, err, session.ErrSessionNotExisted)
+}
+
+func TestMemoryProviderGC(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	mp := NewMemoryProvider(60)
+	mp.now = func() time.Time { return now }
+
+	_, err := mp.SessionInit(ctx, "old")
+	assert.NoError(t, err)
+	assert.NoError(t, mp.SessionBindOwner(ctx, "user:1", "old", now))
+
+	now = now.Add(30 * time.Second)
+	_, err = mp.SessionInit(ctx, "new")
+	assert.NoError(t, err)
+	assert.NoError(t, mp.SessionBindOwner(ctx, "user:1", "new", now))
+
+	sids, err := mp.SessionOwnerList(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old", "new"}, sids)
+
+	now = now.Add(40 * time.Second)
+	mp.SessionGC(ctx, 60)
+	assert.NotContains(t, mp.sessions, "old")
+	assert.Contains(t, mp.sessions, "new")
+
+	sids, err = mp.SessionOwnerList(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new"}, sids)
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemorySession stores the values as strings, the same as the redis provider does
+type MemorySession struct {
+	sid       string
+	lock      sync.RWMutex
+	values    map[string]string
+	expiresAt time.Time // guarded by the provider lock
+}
+
+func (ms *MemorySession) Set(ctx context.Context, key, value interface{}) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.values[fmt.Sprintf("%v", key)] = fmt.Sprintf("%v", value)
+	return nil
+}
+
+func (ms *MemorySession) Get(ctx context.Context, key interface{}) interface{} {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	// The redis provider returns an empty string for a missing field
+	return ms.values[fmt.Sprintf("%v", key)]
+}
+
+func (ms *MemorySession) Delete(ctx context.Context, key interface{}) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.values, fmt.Sprintf("%v", key))
+	return nil
+}
+
+func (ms *MemorySession) Incr(ctx context.Context, key interface{}) (int64, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	k := fmt.Sprintf("%v", key)
+	value, err := parseInt(ms.values[k])
+	if err != nil {
+		return 0, err
+	}
+	value++
+	ms.values[k] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+// parseInt parses a session value as an integer, a missing value is 0 as with HINCRBY
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("session value %q is not an integer", s)
+	}
+	return value, nil
+}
+
+func (ms *MemorySession) SessionID() string {
+	return ms.sid
+}
@@ -21,26 +21,26 @@ type RedisProvider struct {
 	maxLifeTime session.MaxLifeTime
 }
 
-func (rp *RedisProvider) SessionInit(sid string) (session.Session, error) {
-	defer locking.Lock(context.Background(), sid)()
+func (rp *RedisProvider) SessionInit(ctx context.Context, sid string) (session.Session, error) {
+	defer locking.Lock(ctx, sid)()
 
 	// Calculate the expiration time based on maxLifeTime
 	expiration := time.Duration(rp.maxLifeTime) * time.Second
 
 	// Set the session key with expiration
-	if err := rp.memRepo.HSetEx(sid, "init", 1, expiration); err != nil {
+	if err := rp.memRepo.HSetEx(ctx, sid, "init", 1, expiration); err != nil {
 		return nil, err
 	}
 
 	return &RedisSession{sid: sid, memRepo: rp.memRepo}, nil
 }
 
-func (rp *RedisProvider) SessionRead(sid string) (session.Session, error) {
-	defer locking.Lock(context.Background(), sid)()
+func (rp *RedisProvider) SessionRead(ctx context.Context, sid string) (session.Session, error) {
+	defer locking.Lock(ctx, sid)()
 
 	// Refresh the session expiration time
 	expiration := time.Duration(rp.maxLifeTime) * time.Second
-	exists, err := rp.memRepo.Expire(sid, expiration)
+	exists, err := rp.memRepo.Expire(ctx, sid, expiration)
 	if err != nil {
 		return nil, err
 	}
@@ -51,13 +51,57 @@ func (rp *RedisProvider) SessionRead(sid string) (session.Session, error) {
 	return &RedisSession{sid: sid, memRepo: rp.memRepo}, nil
 }
 
-func (rp *RedisProvider) SessionDestroy(sid string) error {
-	defer locking.Lock(context.Background(), sid)()
+func (rp *RedisProvider) SessionPeek(ctx context.Context, sid string) (session.Session, error) {
+	exists, err := rp.memRepo.Exists(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, session.ErrSessionNotExisted
+	}
+
+	return &RedisSession{sid: sid, memRepo: rp.memRepo}, nil
+}
+
+func (rp *RedisProvider) SessionDestroy(ctx context.Context, sid string) error {
+	defer locking.Lock(ctx, sid)()
 
-	_, err := rp.memRepo.Del(sid)
+	_, err := rp.memRepo.Del(ctx, sid)
 	return err
 }
 
-func (rp *RedisProvider) SessionGC(maxLifeTime session.MaxLifeTime) {
+func (rp *RedisProvider) SessionGC(ctx context.Context, maxLifeTime session.MaxLifeTime) {
 	// Not implemented for Redis as it has its own expiration mechanism
 }
+
+// ownerKey is a sorted set of the owner's session IDs scored by creation time.
+// Expired session IDs are dropped by the manager when it lists them,
+// and the set expires a session lifetime after the owner's last login or request.
+func ownerKey(owner string) string {
+	return "session_owner:" + owner
+}
+
+func (rp *RedisProvider) SessionBindOwner(ctx context.Context, owner, sid string, createdAt time.Time) error {
+	if _, err := rp.memRepo.ZAdd(ctx, ownerKey(owner), float64(createdAt.UnixNano()), sid); err != nil {
+		return err
+	}
+	return rp.SessionTouchOwner(ctx, owner)
+}
+
+func (rp *RedisProvider) SessionTouchOwner(ctx context.Context, owner string) error {
+	expiration := time.Duration(rp.maxLifeTime) * time.Second
+	_, err := rp.memRepo.Expire(ctx, ownerKey(owner), expiration)
+	return err
+}
+
+func (rp *RedisProvider) SessionUnbindOwner(ctx context.Context, owner string, sids ...string) error {
+	if len(sids) == 0 {
+		return nil
+	}
+	_, err := rp.memRepo.ZRem(ctx, ownerKey(owner), sids...)
+	return err
+}
+
+func (rp *RedisProvider) SessionOwnerList(ctx context.Context, owner string) ([]string, error) {
+	return rp.memRepo.ZRange(ctx, ownerKey(owner))
+}
@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/a5932016/go-ddd-example/repository"
@@ -11,18 +12,22 @@ type RedisSession struct {
 	memRepo repository.MemRepository
 }
 
-func (rs *RedisSession) Set(key, value interface{}) error {
-	_, err := rs.memRepo.HSet(rs.sid, key, value)
+func (rs *RedisSession) Set(ctx context.Context, key, value interface{}) error {
+	_, err := rs.memRepo.HSet(ctx, rs.sid, key, value)
 	return err
 }
 
-func (rs *RedisSession) Get(key interface{}) interface{} {
-	v, _ := rs.memRepo.HGet(rs.sid, fmt.Sprintf("%v", key))
+func (rs *RedisSession) Get(ctx context.Context, key interface{}) interface{} {
+	v, _ := rs.memRepo.HGet(ctx, rs.sid, fmt.Sprintf("%v", key))
 	return v
 }
 
-func (rs *RedisSession) Delete(key interface{}) error {
-	_, err := rs.memRepo.HDel(rs.sid, fmt.Sprintf("%v", key))
+func (rs *RedisSession) Incr(ctx context.Context, key interface{}) (int64, error) {
+	return rs.memRepo.HIncrBy(ctx, rs.sid, fmt.Sprintf("%v", key), 1)
+}
+
+func (rs *RedisSession) Delete(ctx context.Context, key interface{}) error {
+	_, err := rs.memRepo.HDel(ctx, rs.sid, fmt.Sprintf("%v", key))
 	return err
 }
 
@@ -7,7 +7,12 @@ import (
 	"github.com/a5932016/go-ddd-example/repository"
 	"github.com/a5932016/go-ddd-example/repository/casbin"
 	"github.com/a5932016/go-ddd-example/repository/fs"
+	"github.com/a5932016/go-ddd-example/repository/notifier"
+	"github.com/a5932016/go-ddd-example/repository/oidc"
 	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/hasher"
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/a5932016/go-ddd-example/viewModel"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,6 +20,11 @@ import (
 type Handler interface {
 	Auth
 	User
+	Division
+	Permission
+	Role
+	APIKey
+	AuditLog
 }
 
 // NewHandler new handler
@@ -23,7 +33,12 @@ func NewHandler(
 	memRepo repository.MemRepository,
 	perRepo *casbin.PERRepository,
 	fsRepo fs.FSRepository,
+	notifier notifier.Notifier,
 	sessionManager *session.Manager,
+	jwtSigner *jwt.Signer,
+	oidcProvider *oidc.Provider,
+	passwordPolicy model.PasswordPolicy,
+	passwordHasher *hasher.Hasher,
 	permissionsHandler model.PermissionsHandler,
 ) *HandlerConstructor {
 	h := &HandlerConstructor{
@@ -31,7 +46,12 @@ func NewHandler(
 		memRepo:            memRepo,
 		perRepo:            perRepo,
 		fsRepo:             fsRepo,
+		notifier:           notifier,
 		sessionManager:     sessionManager,
+		jwtSigner:          jwtSigner,
+		oidcProvider:       oidcProvider,
+		passwordPolicy:     passwordPolicy,
+		passwordHasher:     passwordHasher,
 		permissionsHandler: permissionsHandler,
 	}
 
@@ -44,18 +64,89 @@ type HandlerConstructor struct {
 	memRepo            repository.MemRepository
 	perRepo            *casbin.PERRepository
 	fsRepo             fs.FSRepository
+	notifier           notifier.Notifier
 	sessionManager     *session.Manager
+	jwtSigner          *jwt.Signer    // nil unless the jwt strategy is configured
+	oidcProvider       *oidc.Provider // nil unless single sign-on is configured
+	passwordPolicy     model.PasswordPolicy
+	passwordHasher     *hasher.Hasher
 	permissionsHandler model.PermissionsHandler
 }
 
 type Auth interface {
-	Login(c context.Context, account, password string) (sessionId string, user model.User, err error)
+	Login(c context.Context, account, password string, client session.ClientInfo) (model.LoginResult, error)
+	VerifyLoginTwoFactor(c context.Context, preAuthToken, code string, client session.ClientInfo) (model.LoginResult, error)
+	EnrollLoginTwoFactor(c context.Context, preAuthToken string) (secret, uri string, err error)
 	Logout(c context.Context) (err error)
+	RefreshTokens(c context.Context, refreshToken string) (model.LoginResult, error)
+	RevokeRefreshToken(c context.Context, refreshToken string) error
+	JWKS() jwt.JWKSet
+	OIDCLoginURL(c context.Context) (authURL, state string, err error)
+	OIDCLinkURL(c context.Context) (authURL, state string, err error)
+	OIDCCallback(c context.Context, state, code string, client session.ClientInfo) (model.LoginResult, error)
 	ForgotPassword(c *gin.Context, account string) (resetToken string, err error)
+	RequestPasswordReset(c context.Context, email, ip string) error
 	ResetPassword(c context.Context, resetToken, password string) error
+	ChangePassword(c context.Context, currentPassword, password string) error
+	IssueCSRFToken(c context.Context, sessionID string) (string, error)
+	VerifyCSRFToken(c context.Context, sessionID, token string) error
+	ListSessions(c context.Context) ([]session.SessionInfo, error)
+	RevokeSession(c context.Context, handle string) error
+	RevokeOtherSessions(c context.Context) (revoked int, err error)
+	RevokeUserSessions(c context.Context, id uint) (revoked int, err error)
+	UnlockUser(c context.Context, id uint) error
+	SetupTwoFactor(c context.Context) (secret, uri string, err error)
+	EnableTwoFactor(c context.Context, code string) (recoveryCodes []string, err error)
+	DisableTwoFactor(c context.Context, code string) error
+	RegenerateRecoveryCodes(c context.Context, code string) (recoveryCodes []string, err error)
+	RequireTwoFactor(c context.Context, id uint, required bool) error
 }
 
 type User interface {
 	GetUser(c context.Context, id uint) (user model.User, err error)
-	GetRequestUserFromSID(sessionID string) (model.User, error)
+	ListUsers(c context.Context, opt model.EntityOption) (users []model.User, total int64, err error)
+	CreateUser(c context.Context, body viewModel.CreateUser) (user model.User, err error)
+	UpdateUser(c context.Context, id uint, body viewModel.UpdateUser) (user model.User, err error)
+	DeleteUser(c context.Context, id uint) error
+	RestoreUser(c context.Context, id uint) error
+	PurgeUser(c context.Context, id uint) error
+	GetRequestUserFromSID(c context.Context, sessionID string) (model.User, error)
+	GetRequestUserFromAccessToken(c context.Context, accessToken string) (user model.User, family string, err error)
+}
+
+type APIKey interface {
+	CreateAPIKey(c context.Context, body viewModel.CreateAPIKey) (key model.APIKey, token string, err error)
+	ListAPIKeys(c context.Context) ([]model.APIKey, error)
+	DeleteAPIKey(c context.Context, id uint) error
+	GetRequestUserFromAPIKey(c context.Context, token string) (model.User, model.APIKey, error)
+}
+
+type AuditLog interface {
+	ListAuditLogs(c context.Context, filter model.AuditLogFilter, opt model.EntityOption) (entries []model.AuditLog, total int64, err error)
+	AuditLogRetention(c context.Context)
+}
+
+type Division interface {
+	GetDivision(c context.Context, id uint) (division model.Division, err error)
+	ListDivisions(c context.Context, opt model.EntityOption) (divisions []model.Division, total int64, err error)
+	CreateDivision(c context.Context, body viewModel.CreateDivision) (division model.Division, err error)
+	UpdateDivision(c context.Context, id uint, body viewModel.UpdateDivision) (division model.Division, err error)
+	DeleteDivision(c context.Context, id uint) error
+	UpdateDivisionUsers(c context.Context, id uint, userIDs []uint) (division model.Division, err error)
+}
+
+type Permission interface {
+	GetPermissionCatalog(c context.Context) []model.Permission
+	GetDivisionPermissions(c context.Context, id uint) (permissions []model.Permission, err error)
+	UpdateDivisionPermissions(c context.Context, id uint, permissions []model.Permission) ([]model.Permission, error)
+}
+
+type Role interface {
+	ListDivisionRoles(c context.Context, id uint) (assignments []model.RoleAssignment, err error)
+	AssignDivisionRole(c context.Context, id uint, body viewModel.AssignDivisionRole) error
+	RevokeDivisionRole(c context.Context, id uint, role string, userID uint) error
+	GetDivisionRolePermissions(c context.Context, id uint, role string) (permissions []model.Permission, err error)
+	UpdateDivisionRolePermissions(c context.Context, id uint, role string, permissions []model.Permission) ([]model.Permission, error)
+	AddDivisionRoleInheritance(c context.Context, id uint, role, parent string) error
+	DeleteDivisionRoleInheritance(c context.Context, id uint, role, parent string) error
 }
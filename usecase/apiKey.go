@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits the last used time writes to one per interval
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey creates an API key of the request user and returns its token, which is shown only once
+func (h HandlerConstructor) CreateAPIKey(c context.Context, body viewModel.CreateAPIKey) (key model.APIKey, token string, err error) {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	for _, scope := range body.Scopes {
+		if !h.permissionsHandler.IsAvailable(scope.Resource, scope.Action) {
+			return model.APIKey{}, "", customerror.InvalidAPIKeyScope
+		}
+	}
+
+	token, prefix, secretHash, err := model.NewAPIKeyToken()
+	if err != nil {
+		return model.APIKey{}, "", errors.Wrap(err, "model.NewAPIKeyToken")
+	}
+
+	key = model.APIKey{
+		UserID:     requester.ID,
+		Name:       model.NormalizeSpaces(body.Name),
+		Prefix:     prefix,
+		SecretHash: secretHash,
+		Scopes:     body.Scopes,
+	}
+	if body.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, int(*body.ExpiresInDays))
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := h.dbRepo.CreateAPIKey(c, &key); err != nil {
+		return model.APIKey{}, "", errors.Wrap(err, "dbRepo.CreateAPIKey")
+	}
+
+	h.audit(c, "api_key.create", "", key.ID, nil, key)
+
+	return key, token, nil
+}
+
+// ListAPIKeys returns the request user's API keys
+func (h HandlerConstructor) ListAPIKeys(c context.Context) ([]model.APIKey, error) {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := h.dbRepo.ListAPIKeys(c, requester.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "dbRepo.ListAPIKeys")
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of the request user's API keys
+func (h HandlerConstructor) DeleteAPIKey(c context.Context, id uint) error {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dbRepo.DeleteAPIKey(c, requester.ID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, "dbRepo.DeleteAPIKey")
+	}
+
+	h.audit(c, "api_key.delete", "", id, nil, nil)
+	return nil
+}
+
+// GetRequestUserFromAPIKey returns the owner of the API key token with the key, whose scopes further restrict the owner
+func (h HandlerConstructor) GetRequestUserFromAPIKey(c context.Context, token string) (model.User, model.APIKey, error) {
+	prefix, secret, ok := model.ParseAPIKeyToken(token)
+	if !ok {
+		return model.User{}, model.APIKey{}, customerror.InvalidAPIKey
+	}
+
+	key, err := h.dbRepo.GetAPIKeyByPrefix(c, prefix)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, model.APIKey{}, customerror.InvalidAPIKey
+		}
+		return model.User{}, model.APIKey{}, errors.Wrap(err, "dbRepo.GetAPIKeyByPrefix")
+	}
+
+	now := time.Now()
+	if !key.VerifySecret(secret) || key.IsExpired(now) {
+		return model.User{}, model.APIKey{}, customerror.InvalidAPIKey
+	}
+
+	// A deleted owner takes its keys with it
+	user, err := h.dbRepo.GetUser(c, key.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.User{}, model.APIKey{}, customerror.InvalidAPIKey
+		}
+		return model.User{}, model.APIKey{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := h.dbRepo.TouchAPIKey(c, key.ID, now); err != nil {
+			return model.User{}, model.APIKey{}, errors.Wrap(err, "dbRepo.TouchAPIKey")
+		}
+		key.LastUsedAt = &now
+	}
+
+	return user, key, nil
+}
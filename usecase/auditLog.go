@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/pkg/errors"
+)
+
+// auditLogRetentionInterval is how often the expired audit logs are purged
+const auditLogRetentionInterval = time.Hour
+
+// audit records the action of the request user on the target, with the changes from before to after
+func (h HandlerConstructor) audit(c context.Context, action string, resource model.Resource, targetID interface{}, before, after interface{}) {
+	h.auditEntry(c, repository.NewAuditLog(c, action, resource, targetID), before, after)
+}
+
+// auditEntry records the entry with the changes from before to after, either may be nil
+func (h HandlerConstructor) auditEntry(c context.Context, entry model.AuditLog, before, after interface{}) {
+	repository.WriteAuditLog(c, h.dbRepo, entry, before, after)
+}
+
+func (h HandlerConstructor) ListAuditLogs(c context.Context, filter model.AuditLogFilter, opt model.EntityOption) (entries []model.AuditLog, total int64, err error) {
+	entries, total, err = h.dbRepo.ListAuditLogs(c, filter, opt)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "dbRepo.ListAuditLogs")
+	}
+	return entries, total, nil
+}
+
+// AuditLogRetention purges the audit logs older than the retention until c is done,
+// keeping them forever without a retention
+func (h HandlerConstructor) AuditLogRetention(c context.Context) {
+	retention := time.Duration(config.Env.AuditLog.RetentionDays) * 24 * time.Hour
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(auditLogRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := h.dbRepo.PurgeAuditLogs(c, time.Now().Add(-retention))
+		if err != nil {
+			log.WithError(err).Error("dbRepo.PurgeAuditLogs")
+		} else if purged > 0 {
+			log.Infof("purged %d audit logs older than %d days", purged, config.Env.AuditLog.RetentionDays)
+		}
+
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
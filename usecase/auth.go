@@ -2,11 +2,14 @@ package usecase
 
 import (
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/a5932016/go-ddd-example/config"
 	"github.com/a5932016/go-ddd-example/customerror"
 	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
 	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/log"
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
@@ -16,57 +19,117 @@ import (
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
-func (h HandlerConstructor) Login(c context.Context, account, password string) (sessionId string, user model.User, err error) {
+func (h HandlerConstructor) Login(c context.Context, account, password string, client session.ClientInfo) (model.LoginResult, error) {
+	// Check lockout
+	if err := h.checkLoginLock(c, account, client.IP); err != nil {
+		return model.LoginResult{}, err
+	}
+
 	// Get user
-	user, err = h.dbRepo.GetUserByAccount(account)
+	user, err := h.dbRepo.GetUserByAccount(c, account)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", model.User{}, customerror.AccountNotFound
+			return model.LoginResult{}, h.loginFailure(c, account, client.IP, customerror.AccountNotFound)
 		}
-		return "", model.User{}, errors.Wrap(err, "dbRepo.GetUserByAccount")
+		return model.LoginResult{}, errors.Wrap(err, "dbRepo.GetUserByAccount")
 	}
 
 	// Verify password
-	if !model.VerifyPassword(password, user.Password) {
-		return "", model.User{}, customerror.WrongPassword
+	ok, rehash := h.verifyPassword(c, password, user.Password)
+	if !ok {
+		return model.LoginResult{}, h.loginFailure(c, account, client.IP, customerror.WrongPassword)
+	}
+	if rehash {
+		h.rehashPassword(c, user, password)
 	}
 
-	tx := h.dbRepo.Begin()
-	defer tx.Rollback()
+	// Wait for the second factor
+	if user.TOTPEnabled || user.TOTPRequired {
+		return h.startPreAuth(c, user)
+	}
 
-	// txPer, closeTx, err := h.perRepo.BeginWithTx(tx.DB())
-	// if err != nil {
-	// 	return "", model.User{}, errors.Wrap(err, "perRepo.BeginWithTx")
-	// }
-	// defer closeTx(c)
+	if err := h.resetLoginFailures(c, account); err != nil {
+		return model.LoginResult{}, err
+	}
 
-	// for index := range user.Divisions {
-	// 	policies, err := txPer.GetPolicies(user.Divisions[index].GetPrefixedNameID())
-	// 	if err != nil {
-	// 		return "", model.User{}, errors.Wrap(err, "txPer.GetPolicies")
-	// 	}
-	// 	user.Divisions[index].Permissions, err = h.permissionsHandler.CasbinPoliciesToPermissions(policies)
-	// 	if err != nil {
-	// 		return "", model.User{}, errors.Wrap(err, "model.CasbinPoliciesToPermissions")
-	// 	}
-	// }
+	return h.startSession(c, user, client)
+}
+
+// startSession signs the user in with a new session, or with new tokens in the jwt strategy
+func (h HandlerConstructor) startSession(c context.Context, user model.User, client session.ClientInfo) (model.LoginResult, error) {
+	entry := repository.NewAuditLog(c, "auth.login", model.ResourceUser, user.ID)
+	entry.ActorID = user.ID
+	h.auditEntry(c, entry, nil, nil)
 
-	// Set session
-	sc, err := h.sessionManager.SessionStart("")
+	if config.Env.Auth.Strategy == "jwt" {
+		return h.issueTokens(c, user, "")
+	}
+
+	sc, err := h.sessionManager.SessionStartForOwner(c, user.GetPrefixedID(), client)
 	if err != nil {
-		return "", model.User{}, err
+		return model.LoginResult{}, err
+	}
+
+	if err := h.setSessionUser(c, sc.Session, &user); err != nil {
+		return model.LoginResult{}, err
 	}
 
-	userStr, err := h.stringifyUser(user)
+	return model.LoginResult{SessionID: sc.Session.SessionID(), User: user}, nil
+}
+
+// loginFailure records the failed login and returns its error, or the lockout it triggered
+func (h HandlerConstructor) loginFailure(c context.Context, account, ip string, failure error) error {
+	h.auditEntry(c, repository.NewAuditLog(c, "auth.login_failed", model.ResourceUser, nil), nil,
+		map[string]interface{}{"account": account, "reason": failure.Error()})
+
+	locked, err := h.recordLoginFailure(c, account, ip)
 	if err != nil {
-		return "", model.User{}, errors.Wrap(err, "stringifyUser")
+		return err
+	}
+	if locked {
+		return customerror.AccountLocked
 	}
+	return failure
+}
 
-	if err := sc.Session.Set(SIDUser, userStr); err != nil {
-		return "", model.User{}, errors.Wrap(err, "Session.Set(user)")
+// setSessionUser caches the user with its division permissions in the session, stamped with the current version
+func (h HandlerConstructor) setSessionUser(c context.Context, s session.Session, user *model.User) error {
+	// Read the version first, so a change made meanwhile triggers another reload
+	version, err := h.currentUserVersion(c, user.ID)
+	if err != nil {
+		return err
 	}
 
-	return sc.Session.SessionID(), user, nil
+	for index := range user.Divisions {
+		domain := user.Divisions[index].GetPrefixedNameID()
+		policies, err := h.perRepo.GetPolicies(c, domain, domain)
+		if err != nil {
+			return errors.Wrap(err, "perRepo.GetPolicies")
+		}
+		rolePolicies, err := h.perRepo.GetImplicitPermissions(c, user.GetPrefixedID(), domain)
+		if err != nil {
+			return errors.Wrap(err, "perRepo.GetImplicitPermissions")
+		}
+		policies = append(policies, rolePolicies...)
+		user.Divisions[index].Permissions, err = h.permissionsHandler.CasbinPoliciesToPermissions(policies)
+		if err != nil {
+			return errors.Wrap(err, "model.CasbinPoliciesToPermissions")
+		}
+	}
+
+	userStr, err := h.stringifyUser(*user)
+	if err != nil {
+		return errors.Wrap(err, "stringifyUser")
+	}
+
+	if err := s.Set(c, SIDUser, userStr); err != nil {
+		return errors.Wrap(err, "Session.Set(user)")
+	}
+	if err := s.Set(c, SIDUserVersion, version); err != nil {
+		return errors.Wrap(err, "Session.Set(userVersion)")
+	}
+
+	return nil
 }
 
 const (
@@ -74,9 +137,35 @@ const (
 	SIDUser = "user"
 )
 
+// Logout ends the request's session, or in the jwt strategy the refresh token family of its access token.
+// A request authenticated by an API key has neither, and ends nothing.
 func (h HandlerConstructor) Logout(c context.Context) (err error) {
-	sid := c.Value(SID).(string)
-	err = h.sessionManager.SessionDestroy(sid)
+	if config.Env.Auth.Strategy == "jwt" {
+		family, _ := c.Value(RefreshFamily).(string)
+		if family == "" {
+			return nil
+		}
+		requester, err := h.getRequestUser(c)
+		if err != nil {
+			return err
+		}
+		if err := h.revokeRefreshFamily(c, requester.GetPrefixedID(), family); err != nil {
+			return err
+		}
+
+		h.audit(c, "auth.logout", "", nil, nil, nil)
+		return nil
+	}
+
+	sid, _ := c.Value(SID).(string)
+	if sid == "" {
+		return nil
+	}
+	if err = h.sessionManager.SessionDestroy(c, sid); err != nil {
+		return
+	}
+
+	h.audit(c, "auth.logout", "", nil, nil, nil)
 	return
 }
 
@@ -93,7 +182,7 @@ func (h HandlerConstructor) ForgotPassword(c *gin.Context, account string) (rese
 	}
 
 	// Get user
-	user, err := h.dbRepo.GetUserByAccount(account)
+	user, err := h.dbRepo.GetUserByAccount(c, account)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", customerror.AccountNotFound
@@ -101,54 +190,78 @@ func (h HandlerConstructor) ForgotPassword(c *gin.Context, account string) (rese
 		return "", errors.Wrap(err, "dbRepo.GetUserByAccount")
 	}
 
-	// Set session
-	sc, err := h.sessionManager.SessionStart("")
+	resetToken, err = h.issueResetToken(c, model.PasswordResetToken{
+		UserID:     user.ID,
+		ApproverID: approver.ID,
+		ApproverIP: c.ClientIP(),
+		CreatedAt:  time.Now(),
+	})
 	if err != nil {
 		return "", err
 	}
 
-	userStr, err := h.stringifyUser(user)
-	if err != nil {
-		return "", errors.Wrap(err, "stringifyUser")
-	}
+	h.audit(c, "auth.password_reset_approve", model.ResourceUser, user.ID, nil, nil)
 
-	if err := sc.Session.Set(SIDUser, userStr); err != nil {
-		return "", errors.Wrap(err, "Session.Set(user)")
-	}
+	return resetToken, nil
+}
 
-	approverStr, err := h.stringifyUser(approver)
+func (h HandlerConstructor) ResetPassword(c context.Context, resetToken, password string) error {
+	// Read first, so an unacceptable password does not use up the token
+	record, err := h.readResetToken(c, resetToken)
 	if err != nil {
-		return "", errors.Wrap(err, "stringifyUser")
+		return err
 	}
 
-	if err := sc.Session.Set("resetPwdApprover", approverStr); err != nil {
-		return "", errors.Wrap(err, "Session.Set(user)")
+	// The approval is void once the approver is no longer root
+	if record.ApproverID != 0 {
+		approver, err := h.dbRepo.GetUser(c, record.ApproverID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return customerror.InvalidResetToken
+			}
+			return errors.Wrap(err, "dbRepo.GetUser(approver)")
+		}
+		if !approver.IsRoot {
+			return customerror.InvalidResetToken
+		}
 	}
 
-	if err := sc.Session.Set("resetPwdApproverIP", c.ClientIP()); err != nil {
-		return "", errors.Wrap(err, "Session.Set(user)")
+	user, err := h.dbRepo.GetUser(c, record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.InvalidResetToken
+		}
+		return errors.Wrap(err, "dbRepo.GetUser")
 	}
 
-	return sc.Session.SessionID(), nil
-}
-
-func (h HandlerConstructor) ResetPassword(c context.Context, resetToken, password string) error {
-	user, err := h.GetRequestUserFromSID(resetToken)
+	if err := h.validatePassword(c, password, user); err != nil {
+		return err
+	}
+	hashedPassword, err := h.hashPassword(password)
 	if err != nil {
 		return err
 	}
 
-	// Change password
-	user.Password, err = h.hashPassword(password)
-	if err != nil {
+	if _, err := h.consumeResetToken(c, resetToken); err != nil {
 		return err
 	}
 
-	if err := h.dbRepo.UpdateUserPassword(user.ID, user.Password); err != nil {
+	if err := h.dbRepo.UpdateUserPassword(c, user.ID, hashedPassword, h.keepPasswordHistory()); err != nil {
+		return errors.Wrap(err, "dbRepo.UpdateUserPassword")
+	}
+
+	log.FromContext(c).Infof("password of user %d reset, approved by user %d (0 for self-service) from %s", user.ID, record.ApproverID, record.ApproverIP)
+	entry := repository.NewAuditLog(c, "auth.password_reset", model.ResourceUser, user.ID)
+	entry.ActorID = user.ID
+	h.auditEntry(c, entry, nil, map[string]interface{}{"approverId": record.ApproverID})
+
+	// Sign out everywhere with the old password
+	h.bumpUserVersion(c, user.ID)
+	if _, err := h.signOutUser(c, user.GetPrefixedID()); err != nil {
 		return err
 	}
 
-	return h.sessionManager.SessionDestroy(resetToken)
+	return nil
 }
 
 func (h HandlerConstructor) stringifyUser(user model.User) (string, error) {
@@ -161,16 +274,20 @@ func (h HandlerConstructor) stringifyUser(user model.User) (string, error) {
 }
 
 func (h HandlerConstructor) getRequestUser(c context.Context) (model.User, error) {
+	if user, ok := c.Value(model.ContextUser).(model.User); ok {
+		return user, nil
+	}
+
 	sid := c.Value(SID).(string)
 	if !(len(sid) > 0) {
 		return model.User{}, errors.New("sid not found")
 	}
 
-	return h.GetRequestUserFromSID(sid)
+	return h.GetRequestUserFromSID(c, sid)
 }
 
-func (h HandlerConstructor) GetRequestUserFromSID(sessionID string) (model.User, error) {
-	sc, err := h.sessionManager.SessionStart(sessionID)
+func (h HandlerConstructor) GetRequestUserFromSID(c context.Context, sessionID string) (model.User, error) {
+	sc, err := h.sessionManager.SessionStart(c, sessionID)
 	if err != nil {
 		if errors.Is(err, session.ErrSessionNotExisted) {
 			return model.User{}, customerror.InvalidSession
@@ -178,28 +295,79 @@ func (h HandlerConstructor) GetRequestUserFromSID(sessionID string) (model.User,
 		return model.User{}, errors.Wrap(err, "sessionManager.SessionStart")
 	}
 
-	userStr, ok := sc.Session.Get(SIDUser).(string)
+	userStr, ok := sc.Session.Get(c, SIDUser).(string)
 	if !ok {
 		return model.User{}, errors.Wrap(err, "Session.Get(user).(string)")
 	}
+	if userStr == "" {
+		return model.User{}, customerror.InvalidSession
+	}
 
 	var requester model.User
 	if err := json.Unmarshal([]byte(userStr), &requester); err != nil {
 		return model.User{}, errors.Wrap(err, "json.Unmarshal(user)")
 	}
 
-	return requester, nil
+	// Reload the user changed since it was cached
+	version, err := h.currentUserVersion(c, requester.ID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if sessionVersion, _ := sc.Session.Get(c, SIDUserVersion).(string); sessionVersion == version {
+		return requester, nil
+	}
+
+	user, err := h.dbRepo.GetUser(c, requester.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := h.sessionManager.SessionDestroy(c, sessionID); err != nil {
+				return model.User{}, errors.Wrap(err, "sessionManager.SessionDestroy")
+			}
+			return model.User{}, customerror.InvalidSession
+		}
+		return model.User{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	if err := h.setSessionUser(c, sc.Session, &user); err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
 }
 
 func (h HandlerConstructor) hashPassword(password string) (string, error) {
-	passwordBytes, err := model.HashPassword(password)
+	hashedPassword, err := h.passwordHasher.Hash(password)
 	if err != nil {
-		if err == bcrypt.ErrPasswordTooLong {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
 			return "", customerror.PasswordTooLong
 		}
 
-		return "", errors.Wrap(err, fmt.Sprintf("model.HashPassword(%s)", password))
+		return "", errors.Wrap(err, "passwordHasher.Hash")
+	}
+
+	return hashedPassword, nil
+}
+
+// verifyPassword checks the password against the stored hash, which can not match when unreadable,
+// and whether the hash should be upgraded to the current algorithm and parameters
+func (h HandlerConstructor) verifyPassword(c context.Context, password, hashedPassword string) (ok, rehash bool) {
+	ok, rehash, err := h.passwordHasher.Verify(password, hashedPassword)
+	if err != nil {
+		log.FromContext(c).WithError(err).Error("passwordHasher.Verify")
+		return false, false
 	}
+	return ok, rehash
+}
 
-	return string(passwordBytes), nil
+// rehashPassword upgrades the hash of the verified password, unless the password changed meanwhile.
+// The login goes on when it fails, as the old hash still works.
+func (h HandlerConstructor) rehashPassword(c context.Context, user model.User, password string) {
+	hashedPassword, err := h.hashPassword(password)
+	if err != nil {
+		log.FromContext(c).WithError(err).Errorf("rehash password of user %d", user.ID)
+		return
+	}
+	if err := h.dbRepo.UpgradeUserPassword(c, user.ID, user.Password, hashedPassword); err != nil {
+		log.FromContext(c).WithError(err).Errorf("dbRepo.UpgradeUserPassword(%d)", user.ID)
+	}
 }
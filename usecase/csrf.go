@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/pkg/errors"
+)
+
+const SIDCSRFToken = "csrfToken"
+
+// IssueCSRFToken generates the double submit token of a cookie session
+func (h HandlerConstructor) IssueCSRFToken(c context.Context, sessionID string) (string, error) {
+	sc, err := h.sessionManager.SessionPeek(c, sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotExisted) {
+			return "", customerror.InvalidSession
+		}
+		return "", errors.Wrap(err, "sessionManager.SessionPeek")
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	if err := sc.Session.Set(c, SIDCSRFToken, token); err != nil {
+		return "", errors.Wrap(err, "Session.Set(csrfToken)")
+	}
+
+	return token, nil
+}
+
+// VerifyCSRFToken checks the submitted token against the one issued to the session
+func (h HandlerConstructor) VerifyCSRFToken(c context.Context, sessionID, token string) error {
+	sc, err := h.sessionManager.SessionPeek(c, sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotExisted) {
+			return customerror.InvalidSession
+		}
+		return errors.Wrap(err, "sessionManager.SessionPeek")
+	}
+
+	issued, _ := sc.Session.Get(c, SIDCSRFToken).(string)
+	if issued == "" || subtle.ConstantTimeCompare([]byte(issued), []byte(token)) != 1 {
+		return customerror.InvalidCSRFToken
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/repository/casbin"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func (h HandlerConstructor) GetDivision(c context.Context, id uint) (division model.Division, err error) {
+	division, err = h.dbRepo.GetDivision(c, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Division{}, customerror.RecordNotFound
+		}
+		return model.Division{}, errors.Wrap(err, "dbRepo.GetDivision")
+	}
+
+	policies, err := h.perRepo.GetPolicies(c, division.GetPrefixedNameID(), division.GetPrefixedNameID())
+	if err != nil {
+		return model.Division{}, errors.Wrap(err, "perRepo.GetPolicies")
+	}
+	division.Permissions, err = h.permissionsHandler.CasbinPoliciesToPermissions(policies)
+	if err != nil {
+		return model.Division{}, errors.Wrap(err, "permissionsHandler.CasbinPoliciesToPermissions")
+	}
+	return
+}
+
+func (h HandlerConstructor) ListDivisions(c context.Context, opt model.EntityOption) (divisions []model.Division, total int64, err error) {
+	divisions, total, err = h.dbRepo.ListDivisions(c, opt)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "dbRepo.ListDivisions")
+	}
+	return
+}
+
+func (h HandlerConstructor) CreateDivision(c context.Context, body viewModel.CreateDivision) (division model.Division, err error) {
+	division = model.Division{
+		Name:        model.NormalizeSpaces(body.Name),
+		Description: body.Description,
+	}
+
+	err = h.policyTransaction(c, func(tx repository.DBRepository, txPer *casbin.PERRepository) error {
+		if err := tx.CreateDivision(c, &division); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return customerror.DuplicateName
+			}
+			return errors.Wrap(err, "tx.CreateDivision")
+		}
+
+		if policies := model.GetDefaultDivisionCasbinPolicies(division.GetPrefixedNameID()); len(policies) > 0 {
+			if err := txPer.AddPoliciesEx(c, policies); err != nil {
+				return errors.Wrap(err, "txPer.AddPoliciesEx")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Division{}, err
+	}
+
+	h.audit(c, "division.create", model.ResourceDivision, division.ID, nil, division)
+
+	return division, nil
+}
+
+func (h HandlerConstructor) UpdateDivision(c context.Context, id uint, body viewModel.UpdateDivision) (division model.Division, err error) {
+	division, err = h.GetDivision(c, id)
+	if err != nil {
+		return model.Division{}, err
+	}
+	before := division
+
+	if body.Name != nil {
+		division.Name = model.NormalizeSpaces(*body.Name)
+	}
+	if body.Description != nil {
+		division.Description = *body.Description
+	}
+
+	if err := h.dbRepo.UpdateDivision(c, division); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return model.Division{}, customerror.DuplicateName
+		}
+		return model.Division{}, errors.Wrap(err, "dbRepo.UpdateDivision")
+	}
+
+	h.bumpPermissionVersion(c)
+
+	division, err = h.GetDivision(c, id)
+	if err != nil {
+		return model.Division{}, err
+	}
+
+	h.audit(c, "division.update", model.ResourceDivision, id, before, division)
+
+	return division, nil
+}
+
+func (h HandlerConstructor) DeleteDivision(c context.Context, id uint) error {
+	err := h.policyTransaction(c, func(tx repository.DBRepository, txPer *casbin.PERRepository) error {
+		if err := tx.DeleteDivision(c, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return customerror.RecordNotFound
+			}
+			return errors.Wrap(err, "tx.DeleteDivision")
+		}
+
+		if err := txPer.DeleteDomain(c, model.Division{ID: id}.GetPrefixedNameID()); err != nil {
+			return errors.Wrap(err, "txPer.DeleteDomain")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	h.audit(c, "division.delete", model.ResourceDivision, id, nil, nil)
+
+	h.bumpPermissionVersion(c)
+
+	return nil
+}
+
+func (h HandlerConstructor) UpdateDivisionUsers(c context.Context, id uint, userIDs []uint) (division model.Division, err error) {
+	if _, err := h.GetDivision(c, id); err != nil {
+		return model.Division{}, err
+	}
+
+	if err := h.dbRepo.ReplaceDivisionUsers(c, id, userIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Division{}, customerror.SomeRecordsNotFound
+		}
+		return model.Division{}, errors.Wrap(err, "dbRepo.ReplaceDivisionUsers")
+	}
+
+	h.audit(c, "division.users_update", model.ResourceDivision, id, nil, map[string]interface{}{"userIds": userIDs})
+
+	h.bumpPermissionVersion(c)
+
+	return h.GetDivision(c, id)
+}
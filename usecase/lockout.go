@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/pkg/errors"
+)
+
+// Failed logins are counted per account and per IP within the failure window,
+// and either one over its threshold locks further logins for the lockout duration.
+func loginFailureAccountKey(account string) string {
+	return "login_failure_account:" + normalizeAccount(account)
+}
+
+func loginFailureIPKey(ip string) string {
+	return "login_failure_ip:" + ip
+}
+
+func loginLockAccountKey(account string) string {
+	return "login_lock_account:" + normalizeAccount(account)
+}
+
+func loginLockIPKey(ip string) string {
+	return "login_lock_ip:" + ip
+}
+
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// checkLoginLock fails when the account or the IP is locked
+func (h HandlerConstructor) checkLoginLock(c context.Context, account, ip string) error {
+	locked, err := h.memRepo.Exists(c, loginLockAccountKey(account), loginLockIPKey(ip))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.Exists")
+	}
+	if locked > 0 {
+		return customerror.AccountLocked
+	}
+	return nil
+}
+
+// recordLoginFailure counts the failure, locks the account or the IP over the threshold and delays the response
+func (h HandlerConstructor) recordLoginFailure(c context.Context, account, ip string) (locked bool, err error) {
+	window := time.Duration(config.Env.Lockout.FailureWindow) * time.Second
+
+	accountFailures, err := h.memRepo.IncrEx(c, loginFailureAccountKey(account), window)
+	if err != nil {
+		return false, errors.Wrap(err, "memRepo.IncrEx(account)")
+	}
+	ipFailures, err := h.memRepo.IncrEx(c, loginFailureIPKey(ip), window)
+	if err != nil {
+		return false, errors.Wrap(err, "memRepo.IncrEx(ip)")
+	}
+
+	if accountFailures >= int64(config.Env.Lockout.MaxAccountFailures) {
+		if err := h.lockLogin(c, loginLockAccountKey(account), loginFailureAccountKey(account), account, "account"); err != nil {
+			return false, err
+		}
+		locked = true
+	}
+	if ipFailures >= int64(config.Env.Lockout.MaxIPFailures) {
+		if err := h.lockLogin(c, loginLockIPKey(ip), loginFailureIPKey(ip), account, "ip"); err != nil {
+			return false, err
+		}
+		locked = true
+	}
+
+	// Progressive delay, slowing down guessing below the thresholds. A lockout answers at once.
+	if locked {
+		return true, nil
+	}
+	delay := time.Duration(config.Env.Lockout.DelayStep) * time.Millisecond * time.Duration(accountFailures)
+	if maxDelay := time.Duration(config.Env.Lockout.MaxDelay) * time.Millisecond; delay > maxDelay {
+		delay = maxDelay
+	}
+	select {
+	case <-time.After(delay):
+	case <-c.Done():
+	}
+
+	return locked, nil
+}
+
+func (h HandlerConstructor) lockLogin(c context.Context, lockKey, failureKey, account, scope string) error {
+	duration := time.Duration(config.Env.Lockout.Duration) * time.Second
+	if _, err := h.memRepo.Set(c, lockKey, 1, duration); err != nil {
+		return errors.Wrap(err, "memRepo.Set")
+	}
+	if _, err := h.memRepo.Del(c, failureKey); err != nil {
+		return errors.Wrap(err, "memRepo.Del")
+	}
+
+	h.audit(c, "auth.lockout", model.ResourceUser, nil, nil, map[string]interface{}{
+		"account":  normalizeAccount(account),
+		"scope":    scope,
+		"duration": duration.String(),
+	})
+	return nil
+}
+
+// resetLoginFailures clears the account's failures after a successful login
+func (h HandlerConstructor) resetLoginFailures(c context.Context, account string) error {
+	if _, err := h.memRepo.Del(c, loginFailureAccountKey(account)); err != nil {
+		return errors.Wrap(err, "memRepo.Del")
+	}
+	return nil
+}
+
+// UnlockUser lifts the login lockout of the user's account
+func (h HandlerConstructor) UnlockUser(c context.Context, id uint) error {
+	user, err := h.GetUser(c, id)
+	if err != nil {
+		return err
+	}
+
+	cleared, err := h.memRepo.Del(c, loginLockAccountKey(user.Email), loginFailureAccountKey(user.Email))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.Del")
+	}
+
+	h.audit(c, "auth.unlock", model.ResourceUser, user.ID, nil, map[string]interface{}{"cleared": cleared})
+	return nil
+}
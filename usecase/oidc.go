@@ -0,0 +1,317 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/repository/oidc"
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL bounds the time spent at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcLogin is the pending login of a state, kept until the callback
+type oidcLogin struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	// LinkUserID is the signed-in user linking the identity, 0 for a login
+	LinkUserID uint `json:"linkUserId,omitempty"`
+}
+
+// oidcStateKey stores the state hashed, as it travels in URLs
+func oidcStateKey(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return "oidc_state:" + hex.EncodeToString(sum[:])
+}
+
+// OIDCLoginURL starts a single sign-on login, returning the identity provider URL to redirect to
+// and its state, which the caller binds to the browser
+func (h HandlerConstructor) OIDCLoginURL(c context.Context) (authURL, state string, err error) {
+	return h.oidcAuthURL(c, 0)
+}
+
+// OIDCLinkURL starts linking an identity to the request user, who confirms it by being signed in.
+// The callback links the identity instead of signing in.
+func (h HandlerConstructor) OIDCLinkURL(c context.Context) (authURL, state string, err error) {
+	user, err := h.getRequestUser(c)
+	if err != nil {
+		return "", "", err
+	}
+	return h.oidcAuthURL(c, user.ID)
+}
+
+// oidcAuthURL stores the pending login, linking to the user when not 0, and returns its URL and state
+func (h HandlerConstructor) oidcAuthURL(c context.Context, linkUserID uint) (authURL, state string, err error) {
+	if h.oidcProvider == nil {
+		return "", "", customerror.OIDCDisabled
+	}
+
+	state, err = oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, codeChallenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	loginBytes, err := json.Marshal(oidcLogin{Nonce: nonce, CodeVerifier: codeVerifier, LinkUserID: linkUserID})
+	if err != nil {
+		return "", "", errors.Wrap(err, "json.Marshal(oidcLogin)")
+	}
+	if _, err := h.memRepo.Set(c, oidcStateKey(state), string(loginBytes), oidcStateTTL); err != nil {
+		return "", "", errors.Wrap(err, "memRepo.Set")
+	}
+
+	authURL, err = h.oidcProvider.AuthCodeURL(c, state, nonce, codeChallenge)
+	if err != nil {
+		return "", "", errors.Wrap(err, "oidcProvider.AuthCodeURL")
+	}
+
+	return authURL, state, nil
+}
+
+// OIDCCallback finishes the single sign-on login of the state with the authorization code,
+// signing in the linked user, or the user provisioned on the first login.
+// The callback of OIDCLinkURL links the identity to its user instead.
+func (h HandlerConstructor) OIDCCallback(c context.Context, state, code string, client session.ClientInfo) (model.LoginResult, error) {
+	if h.oidcProvider == nil {
+		return model.LoginResult{}, customerror.OIDCDisabled
+	}
+
+	// The state can be used only once
+	loginStr, err := h.memRepo.GetDel(c, oidcStateKey(state))
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.GetDel")
+	}
+	if loginStr == "" {
+		return model.LoginResult{}, customerror.InvalidOIDCLogin
+	}
+	var login oidcLogin
+	if err := json.Unmarshal([]byte(loginStr), &login); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "json.Unmarshal(oidcLogin)")
+	}
+
+	claims, err := h.oidcProvider.Exchange(c, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.FromContext(c).WithError(err).Warning("oidcProvider.Exchange")
+		h.audit(c, "oidc.login_failed", model.ResourceUser, nil, nil, map[string]interface{}{"reason": err.Error()})
+		return model.LoginResult{}, customerror.InvalidOIDCLogin
+	}
+
+	if login.LinkUserID != 0 {
+		return h.linkOIDCIdentity(c, login.LinkUserID, claims)
+	}
+
+	user, err := h.getOIDCUser(c, claims)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	if err := h.syncGroupMappings(c, user, claims.Groups); err != nil {
+		return model.LoginResult{}, err
+	}
+	// Reload the divisions granted by the groups
+	user, err = h.dbRepo.GetUser(c, user.ID)
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	entry := repository.NewAuditLog(c, "oidc.login", model.ResourceUser, user.ID)
+	entry.ActorID = user.ID
+	h.auditEntry(c, entry, nil, map[string]interface{}{"issuer": claims.Issuer, "subject": claims.Subject})
+
+	// Wait for the second factor
+	if user.TOTPEnabled || user.TOTPRequired {
+		return h.startPreAuth(c, user)
+	}
+
+	return h.startSession(c, user, client)
+}
+
+// getOIDCUser returns the user linked to the identity, or provisions a new user of its verified email.
+// An existing account of the email is never linked here, as the identity provider may not own the email:
+// its user links the identity signed in, through OIDCLinkURL.
+func (h HandlerConstructor) getOIDCUser(c context.Context, claims oidc.Claims) (model.User, error) {
+	user, err := h.dbRepo.GetUserByIdentity(c, claims.Issuer, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, errors.Wrap(err, "dbRepo.GetUserByIdentity")
+	}
+
+	if claims.Email == "" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		return model.User{}, customerror.InvalidOIDCLogin
+	}
+
+	_, err = h.dbRepo.GetUserByAccount(c, claims.Email)
+	switch {
+	case err == nil:
+		return model.User{}, customerror.OIDCLinkRequired
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return model.User{}, errors.Wrap(err, "dbRepo.GetUserByAccount")
+	}
+
+	if !config.Env.OIDC.AutoProvision {
+		return model.User{}, customerror.AccountNotFound
+	}
+	user, err = h.provisionOIDCUser(c, claims)
+	if err != nil {
+		return model.User{}, err
+	}
+	if err := h.createOIDCIdentity(c, user.ID, claims); err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// linkOIDCIdentity links the identity to the user who started the link signed in
+func (h HandlerConstructor) linkOIDCIdentity(c context.Context, userID uint, claims oidc.Claims) (model.LoginResult, error) {
+	user, err := h.dbRepo.GetUser(c, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.LoginResult{}, customerror.InvalidOIDCLogin
+		}
+		return model.LoginResult{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	if err := h.createOIDCIdentity(c, user.ID, claims); err != nil {
+		return model.LoginResult{}, err
+	}
+
+	entry := repository.NewAuditLog(c, "oidc.link", model.ResourceUser, user.ID)
+	entry.ActorID = user.ID
+	h.auditEntry(c, entry, nil, map[string]interface{}{"issuer": claims.Issuer, "subject": claims.Subject})
+
+	return model.LoginResult{User: user, Linked: true}, nil
+}
+
+func (h HandlerConstructor) createOIDCIdentity(c context.Context, userID uint, claims oidc.Claims) error {
+	identity := model.UserIdentity{UserID: userID, Issuer: claims.Issuer, Subject: claims.Subject}
+	if err := h.dbRepo.CreateUserIdentity(c, &identity); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// The identity belongs to another user, or to a deleted user
+			return customerror.InvalidOIDCLogin
+		}
+		return errors.Wrap(err, "dbRepo.CreateUserIdentity")
+	}
+	return nil
+}
+
+// provisionOIDCUser creates the user of the identity, with a random password it never uses
+func (h HandlerConstructor) provisionOIDCUser(c context.Context, claims oidc.Claims) (model.User, error) {
+	name := model.NormalizeSpaces(claims.Name)
+	if name == "" {
+		name = claims.Email
+	}
+
+	password, err := oidc.RandomString()
+	if err != nil {
+		return model.User{}, err
+	}
+
+	user := model.User{Email: claims.Email, Name: name}
+	user.Password, err = h.hashPassword(password)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if err := h.dbRepo.CreateUser(c, &user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return model.User{}, customerror.DuplicateUserAccount
+		}
+		return model.User{}, errors.Wrap(err, "dbRepo.CreateUser")
+	}
+
+	entry := repository.NewAuditLog(c, "oidc.provision", model.ResourceUser, user.ID)
+	entry.ActorID = user.ID
+	h.auditEntry(c, entry, nil, user)
+
+	return user, nil
+}
+
+// syncGroupMappings grants the divisions and roles mapped from the user's groups,
+// and revokes the mapped ones of groups the user left
+func (h HandlerConstructor) syncGroupMappings(c context.Context, user model.User, groups []string) error {
+	mappings, err := model.ParseGroupMappings(config.Env.OIDC.GroupMappings)
+	if err != nil {
+		return err
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	inGroup := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		inGroup[group] = true
+	}
+
+	type divisionRole struct {
+		divisionID uint
+		role       string
+	}
+	divisions := make(map[uint]bool)
+	roles := make(map[divisionRole]bool)
+	for _, mapping := range mappings {
+		divisions[mapping.DivisionID] = divisions[mapping.DivisionID] || inGroup[mapping.Group]
+		if mapping.Role != "" {
+			key := divisionRole{mapping.DivisionID, mapping.Role}
+			roles[key] = roles[key] || inGroup[mapping.Group]
+		}
+	}
+
+	member := make(map[uint]bool, len(user.Divisions))
+	for _, division := range user.Divisions {
+		member[division.ID] = true
+	}
+	var addDivisionIDs, removeDivisionIDs []uint
+	for divisionID, granted := range divisions {
+		if granted && !member[divisionID] {
+			addDivisionIDs = append(addDivisionIDs, divisionID)
+		}
+		if !granted && member[divisionID] {
+			removeDivisionIDs = append(removeDivisionIDs, divisionID)
+		}
+	}
+	if err := h.dbRepo.AddUserDivisions(c, user.ID, addDivisionIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.Errorf("group mappings reference a missing division of %v", addDivisionIDs)
+		}
+		return errors.Wrap(err, "dbRepo.AddUserDivisions")
+	}
+	if err := h.dbRepo.RemoveUserDivisions(c, user.ID, removeDivisionIDs); err != nil {
+		return errors.Wrap(err, "dbRepo.RemoveUserDivisions")
+	}
+
+	for key, granted := range roles {
+		domain := model.Division{ID: key.divisionID}.GetPrefixedNameID()
+		if granted {
+			if err := h.perRepo.AddRoleForUserInDomain(c, user.GetPrefixedID(), model.GetPrefixedRole(key.role), domain); err != nil {
+				return errors.Wrap(err, "perRepo.AddRoleForUserInDomain")
+			}
+			continue
+		}
+		if err := h.perRepo.DeleteRoleForUserInDomain(c, user.GetPrefixedID(), model.GetPrefixedRole(key.role), domain); err != nil {
+			return errors.Wrap(err, "perRepo.DeleteRoleForUserInDomain")
+		}
+	}
+
+	h.bumpUserVersion(c, user.ID)
+	return nil
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/pkg/errors"
+)
+
+// ChangePassword replaces the request user's password after checking the current one,
+// and signs out the other sessions
+func (h HandlerConstructor) ChangePassword(c context.Context, currentPassword, password string) error {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return err
+	}
+	// The cached request user carries no password
+	user, err := h.dbRepo.GetUser(c, requester.ID)
+	if err != nil {
+		return errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	if ok, _ := h.verifyPassword(c, currentPassword, user.Password); !ok {
+		h.audit(c, "auth.password_change_failed", model.ResourceUser, user.ID, nil, nil)
+		return customerror.WrongPassword
+	}
+
+	if err := h.validatePassword(c, password, user); err != nil {
+		return err
+	}
+	hashedPassword, err := h.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dbRepo.UpdateUserPassword(c, user.ID, hashedPassword, h.keepPasswordHistory()); err != nil {
+		return errors.Wrap(err, "dbRepo.UpdateUserPassword")
+	}
+
+	h.audit(c, "auth.password_change", model.ResourceUser, user.ID, nil, nil)
+
+	// Sign out the other sessions, and every refresh token in the jwt strategy
+	h.bumpUserVersion(c, user.ID)
+	if config.Env.Auth.Strategy == "jwt" {
+		_, err = h.signOutUser(c, user.GetPrefixedID())
+		return err
+	}
+	if _, err := h.sessionManager.RevokeOwnerSessions(c, user.GetPrefixedID(), c.Value(SID).(string)); err != nil {
+		return errors.Wrap(err, "sessionManager.RevokeOwnerSessions")
+	}
+
+	return nil
+}
+
+// validatePassword checks the password of the user against the policy and the user's latest passwords,
+// returning the reasons in the error info of customerror.InvalidPassword
+func (h HandlerConstructor) validatePassword(c context.Context, password string, user model.User) error {
+	vErr := h.passwordPolicy.Validate(password, user)
+
+	reused, err := h.isRecentPassword(c, password, user)
+	if err != nil {
+		return err
+	}
+	if reused {
+		if vErr == nil {
+			vErr = &model.ValidationError{ItemName: "Password"}
+		}
+		vErr.Reasons = append(vErr.Reasons, "Password was used recently")
+	}
+
+	if vErr == nil {
+		return nil
+	}
+	copyCustomErr := customerror.InvalidPassword
+	copyCustomErr.Message = vErr.Error()
+	copyCustomErr.ErrorInfo = vErr.Reasons
+	return copyCustomErr
+}
+
+// isRecentPassword reports whether the password is the current one or one of the history
+func (h HandlerConstructor) isRecentPassword(c context.Context, password string, user model.User) (bool, error) {
+	if h.passwordPolicy.HistorySize <= 0 || user.ID == 0 {
+		return false, nil
+	}
+
+	if user.Password != "" {
+		if ok, _ := h.verifyPassword(c, password, user.Password); ok {
+			return true, nil
+		}
+	}
+
+	if h.keepPasswordHistory() <= 0 {
+		return false, nil
+	}
+	histories, err := h.dbRepo.ListPasswordHistory(c, user.ID, h.keepPasswordHistory())
+	if err != nil {
+		return false, errors.Wrap(err, "dbRepo.ListPasswordHistory")
+	}
+	for _, history := range histories {
+		if ok, _ := h.verifyPassword(c, password, history.Password); ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// keepPasswordHistory is the number of previous passwords to keep, besides the current one
+func (h HandlerConstructor) keepPasswordHistory() int {
+	return h.passwordPolicy.HistorySize - 1
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/repository/notifier"
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/ulule/limiter/v3"
+	"gorm.io/gorm"
+)
+
+// RequestPasswordReset sends a reset link to the account's email.
+// The result is the same whether the account exists or not.
+func (h HandlerConstructor) RequestPasswordReset(c context.Context, email, ip string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if err := h.throttle(c, config.Env.ResetPassword.IPRate, "reset_password_ip:"+ip); err != nil {
+		return err
+	}
+	if err := h.throttle(c, config.Env.ResetPassword.AccountRate, "reset_password_account:"+email); err != nil {
+		return err
+	}
+
+	// Deliver in the background, so the response time does not tell whether the account exists.
+	// The request context is recycled once the response is sent, so only its logger and audit entry are kept.
+	entry := repository.NewAuditLog(c, "auth.password_reset_request", model.ResourceUser, nil)
+	go h.sendPasswordReset(context.Background(), log.FromContext(c), entry, email, ip)
+
+	return nil
+}
+
+func (h HandlerConstructor) sendPasswordReset(c context.Context, logger *logrus.Entry, entry model.AuditLog, email, ip string) {
+	user, err := h.dbRepo.GetUserByAccount(c, email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.WithError(err).Error("dbRepo.GetUserByAccount")
+		}
+		return
+	}
+
+	// Self-service requests have no approver
+	token, err := h.issueResetToken(c, model.PasswordResetToken{
+		UserID:     user.ID,
+		ApproverIP: ip,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		logger.WithError(err).Error("issueResetToken")
+		return
+	}
+	entry.TargetID = fmt.Sprint(user.ID)
+	h.auditEntry(c, entry, nil, nil)
+
+	link := fmt.Sprintf("%s?resetToken=%s", config.Env.ResetPassword.URL, url.QueryEscape(token))
+	ttl := time.Duration(config.Env.SessionAuth.ResetTokenTTL) * time.Second
+	if err := h.notifier.Notify(c, notifier.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the link below to reset your password. It expires in %s.\n\n%s\n\n"+
+			"If you did not request a password reset, you can ignore this email.", ttl, link),
+	}); err != nil {
+		logger.WithError(err).Error("notifier.Notify")
+	}
+}
+
+// throttle counts a request under key and fails once the rate is exceeded
+func (h HandlerConstructor) throttle(c context.Context, formattedRate, key string) error {
+	rate, err := limiter.NewRateFromFormatted(formattedRate)
+	if err != nil {
+		return errors.Wrapf(err, "limiter.NewRateFromFormatted(%s)", formattedRate)
+	}
+
+	limit, err := limiter.New(h.memRepo.GetAPILimiter(), rate).Get(c, key)
+	if err != nil {
+		return errors.Wrap(err, "limiter.Get")
+	}
+	if limit.Reached {
+		return customerror.TooManyRequests
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/repository/casbin"
+	"github.com/pkg/errors"
+)
+
+func (h HandlerConstructor) GetPermissionCatalog(c context.Context) []model.Permission {
+	return h.permissionsHandler.Catalog()
+}
+
+func (h HandlerConstructor) GetDivisionPermissions(c context.Context, id uint) (permissions []model.Permission, err error) {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return nil, err
+	}
+	return division.Permissions, nil
+}
+
+func (h HandlerConstructor) UpdateDivisionPermissions(c context.Context, id uint, permissions []model.Permission) ([]model.Permission, error) {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := h.permissionsHandler.PermissionsToCasbinPolicies(division.GetPrefixedNameID(), division.GetPrefixedNameID(), permissions)
+	if err := h.replacePolicies(c, division.GetPrefixedNameID(), division.GetPrefixedNameID(), rules); err != nil {
+		return nil, err
+	}
+
+	h.bumpPermissionVersion(c)
+
+	// Read after closeTx released the policy lock
+	updated, err := h.GetDivisionPermissions(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	h.audit(c, "permission.update", model.ResourceDivision, id,
+		map[string]interface{}{"permissions": division.Permissions},
+		map[string]interface{}{"permissions": updated})
+
+	return updated, nil
+}
+
+func (h HandlerConstructor) replacePolicies(c context.Context, subject, domain string, rules [][]string) error {
+	return h.policyTransaction(c, func(tx repository.DBRepository, txPer *casbin.PERRepository) error {
+		if err := txPer.ReplacePolicies(c, subject, domain, rules); err != nil {
+			return errors.Wrap(err, "txPer.ReplacePolicies")
+		}
+		return nil
+	})
+}
+
+// policyTransaction runs fn in a transaction with the permission repository of the transaction,
+// reloading the policies once the transaction is committed or rolled back, and the other instances' only after a commit
+func (h HandlerConstructor) policyTransaction(c context.Context, fn func(tx repository.DBRepository, txPer *casbin.PERRepository) error) (err error) {
+	var closeTx func(c context.Context, committed bool)
+	defer func() {
+		if closeTx != nil {
+			closeTx(c, err == nil)
+		}
+	}()
+
+	return h.dbRepo.Transaction(c, func(tx repository.DBRepository) error {
+		txPer, closeFn, err := h.perRepo.BeginWithTx(tx.DB())
+		if err != nil {
+			return errors.Wrap(err, "perRepo.BeginWithTx")
+		}
+		closeTx = closeFn
+
+		return fn(tx, txPer)
+	})
+}
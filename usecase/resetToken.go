@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/pkg/errors"
+)
+
+// resetTokenKey stores the token hashed, so the store never holds a usable token
+func resetTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "reset_password_token:" + hex.EncodeToString(sum[:])
+}
+
+// issueResetToken stores the record under a new random token, which expires after the reset token TTL
+func (h HandlerConstructor) issueResetToken(c context.Context, record model.PasswordResetToken) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return "", errors.Wrap(err, "json.Marshal(record)")
+	}
+
+	ttl := time.Duration(config.Env.SessionAuth.ResetTokenTTL) * time.Second
+	if _, err := h.memRepo.Set(c, resetTokenKey(token), string(recordBytes), ttl); err != nil {
+		return "", errors.Wrap(err, "memRepo.Set")
+	}
+
+	return token, nil
+}
+
+// readResetToken returns the record of the token without using it up
+func (h HandlerConstructor) readResetToken(c context.Context, token string) (model.PasswordResetToken, error) {
+	values, err := h.memRepo.MGet(c, resetTokenKey(token))
+	if err != nil {
+		return model.PasswordResetToken{}, errors.Wrap(err, "memRepo.MGet")
+	}
+	recordStr, _ := values[0].(string)
+	return parseResetToken(recordStr)
+}
+
+// consumeResetToken returns the record of the token and deletes it, so it can be used only once
+func (h HandlerConstructor) consumeResetToken(c context.Context, token string) (model.PasswordResetToken, error) {
+	recordStr, err := h.memRepo.GetDel(c, resetTokenKey(token))
+	if err != nil {
+		return model.PasswordResetToken{}, errors.Wrap(err, "memRepo.GetDel")
+	}
+	return parseResetToken(recordStr)
+}
+
+func parseResetToken(recordStr string) (model.PasswordResetToken, error) {
+	if recordStr == "" {
+		return model.PasswordResetToken{}, customerror.InvalidResetToken
+	}
+
+	var record model.PasswordResetToken
+	if err := json.Unmarshal([]byte(recordStr), &record); err != nil {
+		return model.PasswordResetToken{}, errors.Wrap(err, "json.Unmarshal(record)")
+	}
+
+	return record, nil
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/viewModel"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func (h HandlerConstructor) ListDivisionRoles(c context.Context, id uint) (assignments []model.RoleAssignment, err error) {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	groupings, err := h.perRepo.GetRoleAssignments(c, division.GetPrefixedNameID())
+	if err != nil {
+		return nil, errors.Wrap(err, "perRepo.GetRoleAssignments")
+	}
+
+	assignments = []model.RoleAssignment{}
+	for _, g := range groupings {
+		// Skip role inheritance rules
+		if !strings.HasPrefix(g[model.GroupingUserIndex], model.UserPrefix) {
+			continue
+		}
+		userID, err := strconv.ParseUint(strings.TrimPrefix(g[model.GroupingUserIndex], model.UserPrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		assignments = append(assignments, model.RoleAssignment{
+			UserID: uint(userID),
+			Role:   strings.TrimPrefix(g[model.GroupingRoleIndex], model.RolePrefix),
+		})
+	}
+
+	return assignments, nil
+}
+
+func (h HandlerConstructor) AssignDivisionRole(c context.Context, id uint, body viewModel.AssignDivisionRole) error {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.dbRepo.GetUser(c, body.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	if err := h.perRepo.AddRoleForUserInDomain(c, user.GetPrefixedID(), model.GetPrefixedRole(body.Role), division.GetPrefixedNameID()); err != nil {
+		return errors.Wrap(err, "perRepo.AddRoleForUserInDomain")
+	}
+
+	h.audit(c, "role.assign", model.ResourceDivision, id, nil, map[string]interface{}{"role": body.Role, "userId": user.ID})
+
+	h.bumpPermissionVersion(c)
+	return nil
+}
+
+func (h HandlerConstructor) RevokeDivisionRole(c context.Context, id uint, role string, userID uint) error {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.perRepo.DeleteRoleForUserInDomain(c, model.User{ID: userID}.GetPrefixedID(), model.GetPrefixedRole(role), division.GetPrefixedNameID()); err != nil {
+		return errors.Wrap(err, "perRepo.DeleteRoleForUserInDomain")
+	}
+
+	h.audit(c, "role.revoke", model.ResourceDivision, id, map[string]interface{}{"role": role, "userId": userID}, nil)
+
+	h.bumpPermissionVersion(c)
+	return nil
+}
+
+func (h HandlerConstructor) GetDivisionRolePermissions(c context.Context, id uint, role string) (permissions []model.Permission, err error) {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := h.perRepo.GetPolicies(c, model.GetPrefixedRole(role), division.GetPrefixedNameID())
+	if err != nil {
+		return nil, errors.Wrap(err, "perRepo.GetPolicies")
+	}
+
+	permissions, err = h.permissionsHandler.CasbinPoliciesToPermissions(policies)
+	if err != nil {
+		return nil, errors.Wrap(err, "permissionsHandler.CasbinPoliciesToPermissions")
+	}
+	return permissions, nil
+}
+
+func (h HandlerConstructor) UpdateDivisionRolePermissions(c context.Context, id uint, role string, permissions []model.Permission) ([]model.Permission, error) {
+	before, err := h.GetDivisionRolePermissions(c, id, role)
+	if err != nil {
+		return nil, err
+	}
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := h.permissionsHandler.PermissionsToCasbinPolicies(model.GetPrefixedRole(role), division.GetPrefixedNameID(), permissions)
+	if err := h.replacePolicies(c, model.GetPrefixedRole(role), division.GetPrefixedNameID(), rules); err != nil {
+		return nil, err
+	}
+
+	h.bumpPermissionVersion(c)
+
+	// Read after closeTx released the policy lock
+	updated, err := h.GetDivisionRolePermissions(c, id, role)
+	if err != nil {
+		return nil, err
+	}
+
+	h.audit(c, "role.permission_update", model.ResourceDivision, id,
+		map[string]interface{}{"role": role, "permissions": before},
+		map[string]interface{}{"role": role, "permissions": updated})
+
+	return updated, nil
+}
+
+// AddDivisionRoleInheritance lets role inherit the permissions of parent in the division
+func (h HandlerConstructor) AddDivisionRoleInheritance(c context.Context, id uint, role, parent string) error {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.perRepo.AddRoleInheritance(c, model.GetPrefixedRole(role), model.GetPrefixedRole(parent), division.GetPrefixedNameID()); err != nil {
+		return errors.Wrap(err, "perRepo.AddRoleInheritance")
+	}
+
+	h.audit(c, "role.inheritance_add", model.ResourceDivision, id, nil, map[string]interface{}{"role": role, "parent": parent})
+
+	h.bumpPermissionVersion(c)
+	return nil
+}
+
+func (h HandlerConstructor) DeleteDivisionRoleInheritance(c context.Context, id uint, role, parent string) error {
+	division, err := h.GetDivision(c, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.perRepo.DeleteRoleInheritance(c, model.GetPrefixedRole(role), model.GetPrefixedRole(parent), division.GetPrefixedNameID()); err != nil {
+		return errors.Wrap(err, "perRepo.DeleteRoleInheritance")
+	}
+
+	h.audit(c, "role.inheritance_delete", model.ResourceDivision, id, map[string]interface{}{"role": role, "parent": parent}, nil)
+
+	h.bumpPermissionVersion(c)
+	return nil
+}
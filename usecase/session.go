@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/pkg/errors"
+)
+
+// ListSessions returns the request user's active sessions
+func (h HandlerConstructor) ListSessions(c context.Context) ([]session.SessionInfo, error) {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := h.sessionManager.ListOwnerSessions(c, requester.GetPrefixedID())
+	if err != nil {
+		return nil, errors.Wrap(err, "sessionManager.ListOwnerSessions")
+	}
+
+	current := session.SessionHandle(c.Value(SID).(string))
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Handle == current
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs out one of the request user's sessions
+func (h HandlerConstructor) RevokeSession(c context.Context, handle string) error {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.sessionManager.RevokeOwnerSession(c, requester.GetPrefixedID(), handle); err != nil {
+		if errors.Is(err, session.ErrSessionNotExisted) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, "sessionManager.RevokeOwnerSession")
+	}
+
+	h.audit(c, "session.revoke", "", handle, nil, nil)
+
+	return nil
+}
+
+// RevokeOtherSessions logs out every session of the request user but the current one
+func (h HandlerConstructor) RevokeOtherSessions(c context.Context) (revoked int, err error) {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err = h.sessionManager.RevokeOwnerSessions(c, requester.GetPrefixedID(), c.Value(SID).(string))
+	if err != nil {
+		return revoked, errors.Wrap(err, "sessionManager.RevokeOwnerSessions")
+	}
+
+	h.audit(c, "session.revoke_others", "", nil, nil, map[string]interface{}{"revoked": revoked})
+
+	return revoked, nil
+}
+
+// RevokeUserSessions force logs out every session and refresh token of the user
+func (h HandlerConstructor) RevokeUserSessions(c context.Context, id uint) (revoked int, err error) {
+	if _, err := h.GetUser(c, id); err != nil {
+		return 0, err
+	}
+
+	revoked, err = h.signOutUser(c, model.User{ID: id}.GetPrefixedID())
+	if err != nil {
+		return revoked, err
+	}
+
+	h.audit(c, "session.revoke_user", model.ResourceUser, id, nil, map[string]interface{}{"revoked": revoked})
+
+	return revoked, nil
+}
@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// AccessClaims authorize a request without a session: the user, whether it is root, and its divisions.
+// They are accepted while the refresh token family is alive and the user version is the one they were issued with.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	IsRoot      bool   `json:"root"`
+	Divisions   []uint `json:"divisions"`
+	Family      string `json:"fam"`
+	UserVersion string `json:"ver"`
+}
+
+// RefreshFamily is the context key of the refresh token family of the request's access token
+const RefreshFamily = "refreshFamily"
+
+// Refresh tokens are stored hashed, under a family that is alive until revoked or unused for the refresh token TTL.
+// A rotated token is remembered as used, so presenting it again revokes its family.
+func refreshTokenKey(token string) string {
+	return "refresh_token:" + hashToken(token)
+}
+
+func refreshTokenUsedKey(token string) string {
+	return "refresh_token_used:" + hashToken(token)
+}
+
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+// refreshFamiliesKey is a sorted set of the owner's families scored by creation time
+func refreshFamiliesKey(owner string) string {
+	return "refresh_families:" + owner
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// issueTokens signs an access token of the user and a refresh token of the family, a new family when empty
+func (h HandlerConstructor) issueTokens(c context.Context, user model.User, family string) (model.LoginResult, error) {
+	if h.jwtSigner == nil {
+		return model.LoginResult{}, errors.New("jwt signer is not configured")
+	}
+
+	now := time.Now()
+	refreshTTL := time.Duration(config.Env.JWT.RefreshTokenTTL) * time.Second
+	if family == "" {
+		var err error
+		if family, err = randomToken(); err != nil {
+			return model.LoginResult{}, err
+		}
+		if _, err := h.memRepo.ZAdd(c, refreshFamiliesKey(user.GetPrefixedID()), float64(now.UnixNano()), family); err != nil {
+			return model.LoginResult{}, errors.Wrap(err, "memRepo.ZAdd")
+		}
+	}
+	if _, err := h.memRepo.Set(c, refreshFamilyKey(family), user.ID, refreshTTL); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.Set(family)")
+	}
+
+	// Access token
+	version, err := h.currentUserVersion(c, user.ID)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	accessTTL := time.Duration(config.Env.JWT.AccessTokenTTL) * time.Second
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.Env.JWT.Issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTTL).Unix(),
+		},
+		IsRoot:      user.IsRoot,
+		Family:      family,
+		UserVersion: version,
+	}
+	for _, division := range user.Divisions {
+		claims.Divisions = append(claims.Divisions, division.ID)
+	}
+	accessToken, err := h.jwtSigner.Sign(claims)
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "jwtSigner.Sign")
+	}
+
+	// Refresh token
+	refreshToken, err := randomToken()
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	recordBytes, err := json.Marshal(model.RefreshToken{UserID: user.ID, Family: family, CreatedAt: now})
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "json.Marshal(record)")
+	}
+	if _, err := h.memRepo.Set(c, refreshTokenKey(refreshToken), string(recordBytes), refreshTTL); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.Set(refreshToken)")
+	}
+
+	return model.LoginResult{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTTL / time.Second),
+	}, nil
+}
+
+// RefreshTokens rotates the refresh token, returning a new access token and refresh token.
+// A refresh token that was already rotated revokes every token of its family.
+func (h HandlerConstructor) RefreshTokens(c context.Context, refreshToken string) (model.LoginResult, error) {
+	recordStr, err := h.memRepo.GetDel(c, refreshTokenKey(refreshToken))
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.GetDel")
+	}
+	if recordStr == "" {
+		if err := h.detectRefreshTokenReuse(c, refreshToken); err != nil {
+			return model.LoginResult{}, err
+		}
+		return model.LoginResult{}, customerror.InvalidRefreshToken
+	}
+
+	var record model.RefreshToken
+	if err := json.Unmarshal([]byte(recordStr), &record); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "json.Unmarshal(record)")
+	}
+
+	alive, err := h.memRepo.Exists(c, refreshFamilyKey(record.Family))
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.Exists")
+	}
+	if alive == 0 {
+		return model.LoginResult{}, customerror.InvalidRefreshToken
+	}
+
+	refreshTTL := time.Duration(config.Env.JWT.RefreshTokenTTL) * time.Second
+	if _, err := h.memRepo.Set(c, refreshTokenUsedKey(refreshToken), record.Family, refreshTTL); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "memRepo.Set(used)")
+	}
+
+	// Reload the user, so the new access token carries its current claims
+	user, err := h.dbRepo.GetUser(c, record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := h.revokeRefreshFamily(c, model.User{ID: record.UserID}.GetPrefixedID(), record.Family); err != nil {
+				return model.LoginResult{}, err
+			}
+			return model.LoginResult{}, customerror.InvalidRefreshToken
+		}
+		return model.LoginResult{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	return h.issueTokens(c, user, record.Family)
+}
+
+// detectRefreshTokenReuse revokes the family of a refresh token that was already rotated
+func (h HandlerConstructor) detectRefreshTokenReuse(c context.Context, refreshToken string) error {
+	values, err := h.memRepo.MGet(c, refreshTokenUsedKey(refreshToken))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.MGet")
+	}
+	family, ok := values[0].(string)
+	if !ok || family == "" {
+		return nil
+	}
+
+	values, err = h.memRepo.MGet(c, refreshFamilyKey(family))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.MGet")
+	}
+	userIDStr, _ := values[0].(string)
+	userID, _ := strconv.ParseUint(userIDStr, 10, 64)
+
+	if err := h.revokeRefreshFamily(c, model.User{ID: uint(userID)}.GetPrefixedID(), family); err != nil {
+		return err
+	}
+
+	h.audit(c, "token.refresh_reuse", model.ResourceUser, userID, nil, map[string]interface{}{"revokedFamily": true})
+	return nil
+}
+
+// RevokeRefreshToken signs out the login of the refresh token
+func (h HandlerConstructor) RevokeRefreshToken(c context.Context, refreshToken string) error {
+	recordStr, err := h.memRepo.GetDel(c, refreshTokenKey(refreshToken))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.GetDel")
+	}
+	if recordStr == "" {
+		return nil
+	}
+
+	var record model.RefreshToken
+	if err := json.Unmarshal([]byte(recordStr), &record); err != nil {
+		return errors.Wrap(err, "json.Unmarshal(record)")
+	}
+
+	return h.revokeRefreshFamily(c, model.User{ID: record.UserID}.GetPrefixedID(), record.Family)
+}
+
+func (h HandlerConstructor) revokeRefreshFamily(c context.Context, owner, family string) error {
+	if _, err := h.memRepo.Del(c, refreshFamilyKey(family)); err != nil {
+		return errors.Wrap(err, "memRepo.Del")
+	}
+	if _, err := h.memRepo.ZRem(c, refreshFamiliesKey(owner), family); err != nil {
+		return errors.Wrap(err, "memRepo.ZRem")
+	}
+	return nil
+}
+
+// revokeRefreshTokens revokes every refresh token family of the owner
+func (h HandlerConstructor) revokeRefreshTokens(c context.Context, owner string) error {
+	families, err := h.memRepo.ZRange(c, refreshFamiliesKey(owner))
+	if err != nil {
+		return errors.Wrap(err, "memRepo.ZRange")
+	}
+
+	keys := []string{refreshFamiliesKey(owner)}
+	for _, family := range families {
+		keys = append(keys, refreshFamilyKey(family))
+	}
+	if _, err := h.memRepo.Del(c, keys...); err != nil {
+		return errors.Wrap(err, "memRepo.Del")
+	}
+	return nil
+}
+
+// signOutUser ends every session and refresh token of the owner, and returns how many sessions were ended
+func (h HandlerConstructor) signOutUser(c context.Context, owner string) (int, error) {
+	revoked, err := h.sessionManager.RevokeOwnerSessions(c, owner, "")
+	if err != nil {
+		return revoked, errors.Wrap(err, "sessionManager.RevokeOwnerSessions")
+	}
+	if err := h.revokeRefreshTokens(c, owner); err != nil {
+		return revoked, err
+	}
+	return revoked, nil
+}
+
+// GetRequestUserFromAccessToken returns the request user carried by the access token claims, and its refresh token family.
+// Only the ID, IsRoot and the division IDs are set.
+// A token whose family was revoked, or whose user or permissions changed since it was issued, is refused,
+// so the client refreshes it with the current claims.
+func (h HandlerConstructor) GetRequestUserFromAccessToken(c context.Context, accessToken string) (user model.User, family string, err error) {
+	if h.jwtSigner == nil {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+
+	var claims AccessClaims
+	if err := h.jwtSigner.Parse(accessToken, &claims); err != nil {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+	if claims.Issuer != config.Env.JWT.Issuer || claims.Family == "" {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+
+	alive, err := h.memRepo.Exists(c, refreshFamilyKey(claims.Family))
+	if err != nil {
+		return model.User{}, "", errors.Wrap(err, "memRepo.Exists")
+	}
+	if alive == 0 {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+	version, err := h.currentUserVersion(c, uint(userID))
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if claims.UserVersion != version {
+		return model.User{}, "", customerror.InvalidAccessToken
+	}
+
+	user = model.User{ID: uint(userID), IsRoot: claims.IsRoot}
+	for _, divisionID := range claims.Divisions {
+		user.Divisions = append(user.Divisions, model.Division{ID: divisionID})
+	}
+	return user, claims.Family, nil
+}
+
+// JWKS returns the public keys verifying the access tokens
+func (h HandlerConstructor) JWKS() jwt.JWKSet {
+	if h.jwtSigner == nil {
+		return jwt.JWKSet{Keys: []jwt.JWK{}}
+	}
+	return h.jwtSigner.JWKS()
+}
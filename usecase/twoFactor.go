@@ -0,0 +1,370 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/a5932016/go-ddd-example/config"
+	"github.com/a5932016/go-ddd-example/customerror"
+	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/singleton/session"
+	"github.com/a5932016/go-ddd-example/util/totp"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// Pre-auth session fields
+	SIDPreAuthUserID     = "preAuthUserId"
+	SIDPreAuthTOTPSecret = "preAuthTOTPSecret" // the secret being enrolled during the login
+
+	// maxPreAuthAttempts is the number of codes a pre-auth session accepts before it is dropped
+	maxPreAuthAttempts = 5
+
+	recoveryCodeCount = 10
+	// totpSkew accepts the codes of the adjacent time steps for clock drift
+	totpSkew = 1
+)
+
+// startPreAuth starts the pre-auth session of a login waiting for the second factor
+func (h HandlerConstructor) startPreAuth(c context.Context, user model.User) (model.LoginResult, error) {
+	lifeTime := time.Duration(config.Env.TwoFactor.PreAuthLifeTime) * time.Second
+	sc, err := h.sessionManager.SessionStartPreAuth(c, lifeTime)
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "sessionManager.SessionStartPreAuth")
+	}
+	if err := sc.Session.Set(c, SIDPreAuthUserID, user.ID); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "Session.Set(preAuthUserId)")
+	}
+
+	twoFactor := model.TwoFactorVerify
+	if !user.TOTPEnabled {
+		twoFactor = model.TwoFactorEnroll
+	}
+
+	return model.LoginResult{PreAuthToken: sc.Session.SessionID(), TwoFactor: twoFactor}, nil
+}
+
+// readPreAuth returns the pre-auth session and the user waiting on it
+func (h HandlerConstructor) readPreAuth(c context.Context, preAuthToken string) (session.SessionCarrier, model.User, error) {
+	sc, err := h.sessionManager.SessionReadPreAuth(c, preAuthToken)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotExisted) {
+			return session.SessionCarrier{}, model.User{}, customerror.InvalidPreAuthToken
+		}
+		return session.SessionCarrier{}, model.User{}, errors.Wrap(err, "sessionManager.SessionReadPreAuth")
+	}
+
+	userIDStr, _ := sc.Session.Get(c, SIDPreAuthUserID).(string)
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		return session.SessionCarrier{}, model.User{}, customerror.InvalidPreAuthToken
+	}
+
+	user, err := h.dbRepo.GetUser(c, uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session.SessionCarrier{}, model.User{}, customerror.InvalidPreAuthToken
+		}
+		return session.SessionCarrier{}, model.User{}, errors.Wrap(err, "dbRepo.GetUser")
+	}
+
+	return sc, user, nil
+}
+
+// EnrollLoginTwoFactor sets up the authenticator of a login required to use two-factor authentication
+func (h HandlerConstructor) EnrollLoginTwoFactor(c context.Context, preAuthToken string) (secret, uri string, err error) {
+	sc, user, err := h.readPreAuth(c, preAuthToken)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", customerror.TwoFactorEnabled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", errors.Wrap(err, "totp.GenerateSecret")
+	}
+	if err := sc.Session.Set(c, SIDPreAuthTOTPSecret, secret); err != nil {
+		return "", "", errors.Wrap(err, "Session.Set(preAuthTOTPSecret)")
+	}
+
+	return secret, totp.ProvisioningURI(config.Env.TwoFactor.Issuer, user.Email, secret), nil
+}
+
+// VerifyLoginTwoFactor upgrades the pre-auth session to a signed in session with a valid code.
+// A login enrolling the user enables two-factor authentication and returns the recovery codes.
+func (h HandlerConstructor) VerifyLoginTwoFactor(c context.Context, preAuthToken, code string, client session.ClientInfo) (model.LoginResult, error) {
+	sc, user, err := h.readPreAuth(c, preAuthToken)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+
+	// Check lockout
+	if err := h.checkLoginLock(c, user.Email, client.IP); err != nil {
+		return model.LoginResult{}, err
+	}
+
+	attempts, err := h.sessionManager.CountPreAuthAttempt(c, sc)
+	if err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "sessionManager.CountPreAuthAttempt")
+	}
+	if attempts > maxPreAuthAttempts {
+		if err := h.sessionManager.SessionDestroy(c, preAuthToken); err != nil {
+			return model.LoginResult{}, errors.Wrap(err, "sessionManager.SessionDestroy")
+		}
+		// A code sent past the limit is still a guess, counted by the lockout
+		return model.LoginResult{}, h.loginFailure(c, user.Email, client.IP, customerror.InvalidPreAuthToken)
+	}
+
+	var recoveryCodes []string
+	if user.TOTPEnabled {
+		ok, err := h.verifyTwoFactorCode(c, user, code, true)
+		if err != nil {
+			return model.LoginResult{}, err
+		}
+		if !ok {
+			return model.LoginResult{}, h.loginFailure(c, user.Email, client.IP, customerror.InvalidTwoFactorCode)
+		}
+	} else {
+		secret, _ := sc.Session.Get(c, SIDPreAuthTOTPSecret).(string)
+		if secret == "" {
+			return model.LoginResult{}, customerror.TwoFactorNotEnrolled
+		}
+		step, ok, err := totp.Validate(secret, code, time.Now(), totpSkew)
+		if err != nil {
+			return model.LoginResult{}, errors.Wrap(err, "totp.Validate")
+		}
+		if !ok {
+			return model.LoginResult{}, h.loginFailure(c, user.Email, client.IP, customerror.InvalidTwoFactorCode)
+		}
+
+		if recoveryCodes, err = h.enableTwoFactor(c, &user, secret, step); err != nil {
+			return model.LoginResult{}, err
+		}
+	}
+
+	if err := h.sessionManager.SessionDestroy(c, preAuthToken); err != nil {
+		return model.LoginResult{}, errors.Wrap(err, "sessionManager.SessionDestroy")
+	}
+	if err := h.resetLoginFailures(c, user.Email); err != nil {
+		return model.LoginResult{}, err
+	}
+
+	result, err := h.startSession(c, user, client)
+	if err != nil {
+		return model.LoginResult{}, err
+	}
+	result.RecoveryCodes = recoveryCodes
+
+	return result, nil
+}
+
+// SetupTwoFactor generates a new secret for the request user, enabled once confirmed by EnableTwoFactor
+func (h HandlerConstructor) SetupTwoFactor(c context.Context) (secret, uri string, err error) {
+	user, err := h.getTwoFactorUser(c)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", customerror.TwoFactorEnabled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", errors.Wrap(err, "totp.GenerateSecret")
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := h.dbRepo.UpdateUserTwoFactor(c, user); err != nil {
+		return "", "", errors.Wrap(err, "dbRepo.UpdateUserTwoFactor")
+	}
+
+	return secret, totp.ProvisioningURI(config.Env.TwoFactor.Issuer, user.Email, secret), nil
+}
+
+// EnableTwoFactor confirms the secret of SetupTwoFactor with a code and returns the recovery codes
+func (h HandlerConstructor) EnableTwoFactor(c context.Context, code string) (recoveryCodes []string, err error) {
+	user, err := h.getTwoFactorUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, customerror.TwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, customerror.TwoFactorNotEnrolled
+	}
+
+	step, ok, err := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if err != nil {
+		return nil, errors.Wrap(err, "totp.Validate")
+	}
+	if !ok {
+		return nil, customerror.InvalidTwoFactorCode
+	}
+
+	return h.enableTwoFactor(c, &user, user.TOTPSecret, step)
+}
+
+// DisableTwoFactor turns off two-factor authentication of the request user, unless it is required
+func (h HandlerConstructor) DisableTwoFactor(c context.Context, code string) error {
+	user, err := h.getTwoFactorUser(c)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return customerror.TwoFactorNotEnrolled
+	}
+	if user.TOTPRequired {
+		return customerror.TwoFactorRequired
+	}
+
+	if ok, err := h.verifyTwoFactorCode(c, user, code, true); err != nil {
+		return err
+	} else if !ok {
+		return customerror.InvalidTwoFactorCode
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	if err := h.dbRepo.UpdateUserTwoFactor(c, user); err != nil {
+		return errors.Wrap(err, "dbRepo.UpdateUserTwoFactor")
+	}
+	if err := h.dbRepo.ReplaceUserRecoveryCodes(c, user.ID, nil); err != nil {
+		return errors.Wrap(err, "dbRepo.ReplaceUserRecoveryCodes")
+	}
+
+	h.audit(c, "2fa.disable", model.ResourceUser, user.ID, nil, nil)
+
+	h.bumpUserVersion(c, user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the request user's recovery codes, a current authenticator code is required
+func (h HandlerConstructor) RegenerateRecoveryCodes(c context.Context, code string) (recoveryCodes []string, err error) {
+	user, err := h.getTwoFactorUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, customerror.TwoFactorNotEnrolled
+	}
+
+	if ok, err := h.verifyTwoFactorCode(c, user, code, false); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, customerror.InvalidTwoFactorCode
+	}
+
+	return h.replaceRecoveryCodes(c, user.ID)
+}
+
+// RequireTwoFactor makes two-factor authentication mandatory for the user, or optional again
+func (h HandlerConstructor) RequireTwoFactor(c context.Context, id uint, required bool) error {
+	user, err := h.GetUser(c, id)
+	if err != nil {
+		return err
+	}
+
+	user.TOTPRequired = required
+	if err := h.dbRepo.UpdateUserTwoFactor(c, user); err != nil {
+		return errors.Wrap(err, "dbRepo.UpdateUserTwoFactor")
+	}
+
+	h.audit(c, "2fa.require", model.ResourceUser, user.ID, nil, map[string]interface{}{"required": required})
+
+	h.bumpUserVersion(c, user.ID)
+
+	// Sign the user in again, enrolling on the way
+	if required && !user.TOTPEnabled {
+		if _, err := h.signOutUser(c, user.GetPrefixedID()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTwoFactorUser reloads the request user, as the session copy lacks the two-factor secrets
+func (h HandlerConstructor) getTwoFactorUser(c context.Context) (model.User, error) {
+	requester, err := h.getRequestUser(c)
+	if err != nil {
+		return model.User{}, err
+	}
+	return h.GetUser(c, requester.ID)
+}
+
+// enableTwoFactor enables the confirmed secret and returns new recovery codes
+func (h HandlerConstructor) enableTwoFactor(c context.Context, user *model.User, secret string, step int64) ([]string, error) {
+	user.TOTPSecret = secret
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	if err := h.dbRepo.UpdateUserTwoFactor(c, *user); err != nil {
+		return nil, errors.Wrap(err, "dbRepo.UpdateUserTwoFactor")
+	}
+
+	recoveryCodes, err := h.replaceRecoveryCodes(c, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.audit(c, "2fa.enable", model.ResourceUser, user.ID, nil, nil)
+
+	h.bumpUserVersion(c, user.ID)
+	return recoveryCodes, nil
+}
+
+func (h HandlerConstructor) replaceRecoveryCodes(c context.Context, userID uint) ([]string, error) {
+	recoveryCodes, err := model.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, errors.Wrap(err, "model.GenerateRecoveryCodes")
+	}
+
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, model.HashRecoveryCode(code))
+	}
+	if err := h.dbRepo.ReplaceUserRecoveryCodes(c, userID, hashes); err != nil {
+		return nil, errors.Wrap(err, "dbRepo.ReplaceUserRecoveryCodes")
+	}
+
+	return recoveryCodes, nil
+}
+
+// verifyTwoFactorCode accepts an authenticator code used for the first time, or an unused recovery code when allowed
+func (h HandlerConstructor) verifyTwoFactorCode(c context.Context, user model.User, code string, allowRecoveryCode bool) (bool, error) {
+	step, ok, err := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if err != nil {
+		return false, errors.Wrap(err, "totp.Validate")
+	}
+	if ok {
+		// A code is accepted once, even within its time step
+		claimed, err := h.dbRepo.ClaimUserTOTPStep(c, user.ID, step)
+		if err != nil {
+			return false, errors.Wrap(err, "dbRepo.ClaimUserTOTPStep")
+		}
+		return claimed, nil
+	}
+
+	if !allowRecoveryCode {
+		return false, nil
+	}
+
+	used, err := h.dbRepo.UseUserRecoveryCode(c, user.ID, model.HashRecoveryCode(code))
+	if err != nil {
+		return false, errors.Wrap(err, "dbRepo.UseUserRecoveryCode")
+	}
+	if used {
+		// Used at login, before the user is signed in
+		entry := repository.NewAuditLog(c, "2fa.recovery_code", model.ResourceUser, user.ID)
+		entry.ActorID = user.ID
+		h.auditEntry(c, entry, nil, nil)
+	}
+	return used, nil
+}
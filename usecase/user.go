@@ -5,12 +5,13 @@ import (
 
 	"github.com/a5932016/go-ddd-example/customerror"
 	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/viewModel"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 func (h HandlerConstructor) GetUser(c context.Context, id uint) (user model.User, err error) {
-	user, err = h.dbRepo.GetUser(id)
+	user, err = h.dbRepo.GetUser(c, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return model.User{}, customerror.RecordNotFound
@@ -19,3 +20,146 @@ func (h HandlerConstructor) GetUser(c context.Context, id uint) (user model.User
 	}
 	return
 }
+
+func (h HandlerConstructor) ListUsers(c context.Context, opt model.EntityOption) (users []model.User, total int64, err error) {
+	users, total, err = h.dbRepo.ListUsers(c, opt)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "dbRepo.ListUsers")
+	}
+	return
+}
+
+func (h HandlerConstructor) CreateUser(c context.Context, body viewModel.CreateUser) (user model.User, err error) {
+	// Check hierarchy permission: only root can create root
+	if body.IsRoot {
+		requester, err := h.getRequestUser(c)
+		if err != nil {
+			return model.User{}, err
+		}
+		if !requester.IsRoot {
+			return model.User{}, customerror.NoHierarchyPermission
+		}
+	}
+
+	user = model.User{
+		Email:  body.Email,
+		Name:   model.NormalizeSpaces(body.Name),
+		IsRoot: body.IsRoot,
+	}
+	if err := h.validatePassword(c, body.Password, user); err != nil {
+		return model.User{}, err
+	}
+	user.Password, err = h.hashPassword(body.Password)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if err := h.dbRepo.CreateUser(c, &user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return model.User{}, customerror.DuplicateUserAccount
+		}
+		return model.User{}, errors.Wrap(err, "dbRepo.CreateUser")
+	}
+
+	h.audit(c, "user.create", model.ResourceUser, user.ID, nil, user)
+
+	return user, nil
+}
+
+func (h HandlerConstructor) UpdateUser(c context.Context, id uint, body viewModel.UpdateUser) (user model.User, err error) {
+	user, err = h.GetUser(c, id)
+	if err != nil {
+		return model.User{}, err
+	}
+	before := user
+
+	// Check hierarchy permission: only root can grant or revoke root
+	if body.IsRoot != nil && *body.IsRoot != user.IsRoot {
+		requester, err := h.getRequestUser(c)
+		if err != nil {
+			return model.User{}, err
+		}
+		if !requester.IsRoot {
+			return model.User{}, customerror.NoHierarchyPermission
+		}
+		user.IsRoot = *body.IsRoot
+	}
+	if body.Email != nil {
+		user.Email = *body.Email
+	}
+	if body.Name != nil {
+		user.Name = model.NormalizeSpaces(*body.Name)
+	}
+
+	if err := h.dbRepo.UpdateUser(c, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return model.User{}, customerror.DuplicateUserAccount
+		}
+		return model.User{}, errors.Wrap(err, "dbRepo.UpdateUser")
+	}
+
+	h.bumpUserVersion(c, id)
+
+	user, err = h.GetUser(c, id)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	h.audit(c, "user.update", model.ResourceUser, id, before, user)
+
+	return user, nil
+}
+
+func (h HandlerConstructor) DeleteUser(c context.Context, id uint) error {
+	if err := h.dbRepo.DeleteUser(c, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, "dbRepo.DeleteUser")
+	}
+
+	h.audit(c, "user.delete", model.ResourceUser, id, nil, nil)
+
+	h.bumpUserVersion(c, id)
+	if _, err := h.signOutUser(c, model.User{ID: id}.GetPrefixedID()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h HandlerConstructor) RestoreUser(c context.Context, id uint) error {
+	if err := h.dbRepo.RestoreUser(c, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return customerror.DuplicateUserAccount
+		}
+		return errors.Wrap(err, "dbRepo.RestoreUser")
+	}
+
+	h.audit(c, "user.restore", model.ResourceUser, id, nil, nil)
+
+	return nil
+}
+
+func (h HandlerConstructor) PurgeUser(c context.Context, id uint) error {
+	if err := h.dbRepo.PurgeUser(c, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerror.RecordNotFound
+		}
+		return errors.Wrap(err, "dbRepo.PurgeUser")
+	}
+
+	if err := h.perRepo.DeleteSubject(c, model.User{ID: id}.GetPrefixedID()); err != nil {
+		return errors.Wrap(err, "perRepo.DeleteSubject")
+	}
+
+	h.audit(c, "user.purge", model.ResourceUser, id, nil, nil)
+
+	h.bumpUserVersion(c, id)
+	if _, err := h.signOutUser(c, model.User{ID: id}.GetPrefixedID()); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a5932016/go-ddd-example/util/log"
+	"github.com/pkg/errors"
+)
+
+// Version stamps of the user snapshot cached in the session.
+// A session whose stamp differs from the current one reloads the user.
+const (
+	SIDUserVersion = "userVersion"
+
+	permissionVersionKey = "permission_version"
+)
+
+func userVersionKey(id uint) string {
+	return fmt.Sprintf("user_version:%d", id)
+}
+
+// currentUserVersion returns the stamp of the user and of the permissions shared by every user
+func (h HandlerConstructor) currentUserVersion(c context.Context, id uint) (string, error) {
+	values, err := h.memRepo.MGet(c, userVersionKey(id), permissionVersionKey)
+	if err != nil {
+		return "", errors.Wrap(err, "memRepo.MGet")
+	}
+
+	userVersion, permissionVersion := "0", "0"
+	if v, ok := values[0].(string); ok {
+		userVersion = v
+	}
+	if v, ok := values[1].(string); ok {
+		permissionVersion = v
+	}
+
+	return fmt.Sprintf("%s:%s", userVersion, permissionVersion), nil
+}
+
+// bumpUserVersion makes the sessions of the user reload it on their next request
+func (h HandlerConstructor) bumpUserVersion(c context.Context, id uint) {
+	if _, err := h.memRepo.Incr(c, userVersionKey(id)); err != nil {
+		log.FromContext(c).WithError(err).Errorf("memRepo.Incr(%s)", userVersionKey(id))
+	}
+}
+
+// bumpPermissionVersion makes every session reload its user on the next request
+func (h HandlerConstructor) bumpPermissionVersion(c context.Context) {
+	if _, err := h.memRepo.Incr(c, permissionVersionKey); err != nil {
+		log.FromContext(c).WithError(err).Errorf("memRepo.Incr(%s)", permissionVersionKey)
+	}
+}
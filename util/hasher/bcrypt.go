@@ -0,0 +1,49 @@
+package hasher
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt hashes in the $2a$<cost>$ modular crypt format.
+// Passwords over 72 bytes are rejected with bcrypt.ErrPasswordTooLong.
+type Bcrypt struct {
+	Cost int
+}
+
+// DefaultBcrypt is the cost the service has always used
+var DefaultBcrypt = Bcrypt{Cost: 12}
+
+func (b Bcrypt) Match(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword, bcrypt.ErrPasswordTooLong:
+		return false, nil
+	}
+	return false, ErrMalformedHash
+}
+
+func (b Bcrypt) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
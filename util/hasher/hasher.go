@@ -0,0 +1,53 @@
+// Package hasher hashes passwords into self-describing encoded strings, PHC format for argon2id
+// and the modular crypt format for bcrypt, and tells when a stored hash should be upgraded.
+package hasher
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Algorithm is a password hashing algorithm with its parameters
+type Algorithm interface {
+	// Match reports whether the encoded hash was made by the algorithm
+	Match(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// Outdated reports whether the encoded hash of the algorithm uses other parameters than the current ones
+	Outdated(encoded string) bool
+}
+
+// New hashes with current, and verifies the hashes of current and of the legacy algorithms
+func New(current Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{current: current, algorithms: append([]Algorithm{current}, legacy...)}
+}
+
+// Hasher hashes new passwords with the current algorithm
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify checks the password against the encoded hash,
+// and whether the hash should be replaced by one of the current algorithm and parameters
+func (h *Hasher) Verify(password, encoded string) (ok, rehash bool, err error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Match(encoded) {
+			continue
+		}
+		ok, err = algorithm.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, algorithm != h.current || algorithm.Outdated(encoded), nil
+	}
+	return false, false, ErrUnknownAlgorithm
+}
@@ -0,0 +1,100 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2id keeps the tests fast
+var testArgon2id = Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	encoded, err := testArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := testArgon2id.Verify("correct horse", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = testArgon2id.Verify("wrong horse", encoded)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// A new salt every time
+	other, err := testArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encoded, other)
+}
+
+func TestArgon2idOutdated(t *testing.T) {
+	encoded, err := testArgon2id.Hash("correct horse")
+	assert.NoError(t, err)
+	assert.False(t, testArgon2id.Outdated(encoded))
+
+	stronger := testArgon2id
+	stronger.Iterations = 2
+	assert.True(t, stronger.Outdated(encoded))
+
+	// Verified with the parameters of the hash, not the current ones
+	ok, err := stronger.Verify("correct horse", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestArgon2idMalformed(t *testing.T) {
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+	} {
+		_, err := testArgon2id.Verify("password", encoded)
+		assert.ErrorIs(t, err, ErrMalformedHash, encoded)
+	}
+}
+
+func TestHasherVerify(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	h := New(testArgon2id, Bcrypt{Cost: bcrypt.MinCost})
+
+	// Existing bcrypt hashes keep working, and are upgraded
+	ok, rehash, err := h.Verify("correct horse", string(legacy))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, rehash, err = h.Verify("wrong horse", string(legacy))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+
+	encoded, err := h.Hash("correct horse")
+	assert.NoError(t, err)
+	ok, rehash, err = h.Verify("correct horse", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	_, _, err = h.Verify("correct horse", "$md5$abc")
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
+
+func TestHasherBcryptCost(t *testing.T) {
+	encoded, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("correct horse")
+	assert.NoError(t, err)
+
+	h := New(Bcrypt{Cost: bcrypt.MinCost + 1})
+	ok, rehash, err := h.Verify("correct horse", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	_, err = h.Hash(strings.Repeat("a", 73))
+	assert.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+}
@@ -0,0 +1,289 @@
+// Package jwt signs and verifies compact JSON Web Tokens with HS256 or RS256
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+// minSecretSize is the HS256 secret size, RFC 7518 section 3.2
+const minSecretSize = 32
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token is expired")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Claims validates the claims of a verified token
+type Claims interface {
+	Valid(now time.Time) error
+}
+
+// RegisteredClaims are the registered claim names, RFC 7519 section 4.1
+type RegisteredClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+// Valid checks the time based claims
+func (c RegisteredClaims) Valid(now time.Time) error {
+	if c.ExpiresAt != 0 && now.Unix() >= c.ExpiresAt {
+		return ErrExpiredToken
+	}
+	if c.NotBefore != 0 && now.Unix() < c.NotBefore {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Signer signs and verifies the tokens of a single key
+type Signer struct {
+	alg        string
+	kid        string
+	secret     []byte
+	privateKey *rsa.PrivateKey // nil for a verifier
+	publicKey  *rsa.PublicKey
+}
+
+// NewHS256Signer signs with a shared secret of at least 32 bytes
+func NewHS256Signer(secret []byte) (*Signer, error) {
+	if len(secret) < minSecretSize {
+		return nil, errors.Errorf("HS256 secret must be at least %d bytes", minSecretSize)
+	}
+	return &Signer{alg: HS256, secret: secret}, nil
+}
+
+// NewRS256Signer signs with the private key, its public key is published by JWKS under kid
+func NewRS256Signer(privateKey *rsa.PrivateKey, kid string) *Signer {
+	return &Signer{alg: RS256, kid: kid, privateKey: privateKey, publicKey: &privateKey.PublicKey}
+}
+
+// NewRS256Verifier only verifies the tokens of the public key, such as one published by another issuer
+func NewRS256Verifier(publicKey *rsa.PublicKey, kid string) *Signer {
+	return &Signer{alg: RS256, kid: kid, publicKey: publicKey}
+}
+
+// ParseHeader returns the algorithm and the key ID of the token, without verifying it
+func ParseHeader(token string) (alg, kid string, err error) {
+	headerPart, _, found := strings.Cut(token, ".")
+	if !found {
+		return "", "", ErrInvalidToken
+	}
+	headerBytes, err := b64.DecodeString(headerPart)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+	var h header
+	if err := json.Unmarshal(headerBytes, &h); err != nil {
+		return "", "", ErrInvalidToken
+	}
+	return h.Alg, h.Kid, nil
+}
+
+// ParseRSAPrivateKeyPEM parses a PKCS #1 or PKCS #8 PEM encoded RSA private key
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "x509.ParsePKCS8PrivateKey")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// DecodeSegment decodes a base64url segment of a token
+func DecodeSegment(segment string) ([]byte, error) {
+	return b64.DecodeString(segment)
+}
+
+// Algorithm returns the signing algorithm
+func (s *Signer) Algorithm() string {
+	return s.alg
+}
+
+// Sign encodes the claims as a signed token
+func (s *Signer) Sign(claims interface{}) (string, error) {
+	headerBytes, err := json.Marshal(header{Alg: s.alg, Typ: "JWT", Kid: s.kid})
+	if err != nil {
+		return "", errors.Wrap(err, "json.Marshal(header)")
+	}
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, "json.Marshal(claims)")
+	}
+
+	signingInput := b64.EncodeToString(headerBytes) + "." + b64.EncodeToString(claimsBytes)
+	signature, err := s.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + b64.EncodeToString(signature), nil
+}
+
+// Parse verifies the token signed by this signer, and decodes and validates its claims.
+// The algorithm of the token header must be the signer's, so a token can not pick a weaker one.
+func (s *Signer) Parse(token string, claims Claims) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	headerBytes, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	var h header
+	if err := json.Unmarshal(headerBytes, &h); err != nil {
+		return ErrInvalidToken
+	}
+	if h.Alg != s.alg || h.Kid != s.kid {
+		return ErrInvalidToken
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if !s.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return ErrInvalidToken
+	}
+
+	claimsBytes, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(claimsBytes, claims); err != nil {
+		return ErrInvalidToken
+	}
+
+	return claims.Valid(time.Now())
+}
+
+func (s *Signer) sign(signingInput []byte) ([]byte, error) {
+	switch s.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	case RS256:
+		if s.privateKey == nil {
+			return nil, errors.New("verifier can not sign")
+		}
+		digest := sha256.Sum256(signingInput)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+		if err != nil {
+			return nil, errors.Wrap(err, "rsa.SignPKCS1v15")
+		}
+		return signature, nil
+	}
+	return nil, errors.Errorf("unsupported algorithm %s", s.alg)
+}
+
+func (s *Signer) verify(signingInput, signature []byte) bool {
+	switch s.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(signingInput)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		digest := sha256.Sum256(signingInput)
+		return rsa.VerifyPKCS1v15(s.publicKey, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+// JWK is a public key, RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// PublicKey decodes the RSA public key
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, errors.Errorf("unsupported key type %s", k.Kty)
+	}
+	n, err := b64.DecodeString(k.N)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode n")
+	}
+	e, err := b64.DecodeString(k.E)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode e")
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public key of an RS256 signer, and no key for a shared secret
+func (s *Signer) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if s.alg != RS256 {
+		return set
+	}
+
+	publicKey := s.publicKey
+	set.Keys = append(set.Keys, JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: RS256,
+		Kid: s.kid,
+		N:   b64.EncodeToString(publicKey.N.Bytes()),
+		E:   b64.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	})
+	return set
+}
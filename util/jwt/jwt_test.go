@@ -0,0 +1,104 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	RegisteredClaims
+	IsRoot bool `json:"root"`
+}
+
+// RFC 7515 appendix A.1, expired long ago, so a verified signature reports the expiration
+func TestParseRFC7515HS256(t *testing.T) {
+	key, err := b64.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	assert.NoError(t, err)
+	signer, err := NewHS256Signer(key)
+	assert.NoError(t, err)
+
+	token := "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9" +
+		".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+		".dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	var claims RegisteredClaims
+	assert.ErrorIs(t, signer.Parse(token, &claims), ErrExpiredToken)
+	assert.Equal(t, "joe", claims.Issuer)
+
+	tampered := strings.Replace(token, ".dBjf", ".dBjg", 1)
+	assert.ErrorIs(t, signer.Parse(tampered, &claims), ErrInvalidToken)
+}
+
+func TestSignParse(t *testing.T) {
+	_, err := NewHS256Signer([]byte("short"))
+	assert.Error(t, err)
+
+	hsSigner, err := NewHS256Signer([]byte(strings.Repeat("s", 32)))
+	assert.NoError(t, err)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsSigner := NewRS256Signer(privateKey, "key-1")
+
+	for _, signer := range []*Signer{hsSigner, rsSigner} {
+		claims := testClaims{
+			RegisteredClaims: RegisteredClaims{Subject: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()},
+			IsRoot:           true,
+		}
+		token, err := signer.Sign(claims)
+		assert.NoError(t, err)
+
+		var parsed testClaims
+		assert.NoError(t, signer.Parse(token, &parsed), signer.Algorithm())
+		assert.Equal(t, claims, parsed)
+
+		expired := claims
+		expired.ExpiresAt = time.Now().Add(-time.Second).Unix()
+		token, err = signer.Sign(expired)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, signer.Parse(token, &parsed), ErrExpiredToken)
+	}
+
+	// A token of another algorithm is rejected
+	token, err := hsSigner.Sign(RegisteredClaims{Subject: "1"})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, rsSigner.Parse(token, &RegisteredClaims{}), ErrInvalidToken)
+	unsigned := b64.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + strings.Split(token, ".")[1] + "."
+	assert.ErrorIs(t, hsSigner.Parse(unsigned, &RegisteredClaims{}), ErrInvalidToken)
+}
+
+func TestJWKS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	set := NewRS256Signer(privateKey, "key-1").JWKS()
+	if assert.Len(t, set.Keys, 1) {
+		assert.Equal(t, "RSA", set.Keys[0].Kty)
+		assert.Equal(t, "key-1", set.Keys[0].Kid)
+		assert.Equal(t, "AQAB", set.Keys[0].E)
+		assert.Equal(t, b64.EncodeToString(privateKey.N.Bytes()), set.Keys[0].N)
+
+		// The published key verifies the tokens
+		publicKey, err := set.Keys[0].PublicKey()
+		assert.NoError(t, err)
+		assert.True(t, privateKey.PublicKey.Equal(publicKey))
+
+		token, err := NewRS256Signer(privateKey, "key-1").Sign(RegisteredClaims{Subject: "1"})
+		assert.NoError(t, err)
+		alg, kid, err := ParseHeader(token)
+		assert.NoError(t, err)
+		assert.Equal(t, RS256, alg)
+		assert.Equal(t, "key-1", kid)
+		verifier := NewRS256Verifier(publicKey, kid)
+		assert.NoError(t, verifier.Parse(token, &RegisteredClaims{}))
+		_, err = verifier.Sign(RegisteredClaims{})
+		assert.Error(t, err)
+	}
+
+	hsSigner, err := NewHS256Signer([]byte(strings.Repeat("s", 32)))
+	assert.NoError(t, err)
+	assert.Empty(t, hsSigner.JWKS().Keys)
+}
@@ -2,4 +2,8 @@ package mGin
 
 const (
 	HeaderKeyKongRequestID = "Kong-Request-Id"
+	HeaderKeyRequestID     = "X-Request-Id"
+
+	// ContextKeyRequestID is the context key of the request ID set by RequestIDMiddleware
+	ContextKeyRequestID = "requestId"
 )
@@ -23,6 +23,16 @@ func (cErr CustomError) WithDeclineCode(code string) CustomError {
 	}
 }
 
+func (cErr CustomError) WithHTTPCode(code int) CustomError {
+	return CustomError{
+		HTTPCode:    code,
+		Code:        cErr.Code,
+		Message:     cErr.Message,
+		ErrorInfo:   cErr.ErrorInfo,
+		DeclineCode: cErr.DeclineCode,
+	}
+}
+
 type Errors struct {
 	Meta Meta      `json:"meta"`
 	Data *struct{} `json:"data"`
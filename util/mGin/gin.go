@@ -100,6 +100,21 @@ func (c *Context) GetSort() *filters.SortFilter {
 	return nil
 }
 
+// GetSortBy returns the sort of the query mapped to its column by the allowed keys, nil for any other key
+func (c *Context) GetSortBy(allowedKeys map[string]string) *filters.SortFilter {
+	sort := c.GetSort()
+	if sort == nil {
+		return nil
+	}
+	if column, ok := allowedKeys[sort.Asc]; ok && len(sort.Asc) > 0 {
+		return &filters.SortFilter{Asc: column}
+	}
+	if column, ok := allowedKeys[sort.Desc]; ok && len(sort.Desc) > 0 {
+		return &filters.SortFilter{Desc: column}
+	}
+	return nil
+}
+
 func (c *Context) WithSort(sort *filters.SortFilter) *Context {
 	if sort != nil {
 		var (
@@ -175,6 +190,10 @@ func (c *Context) Response(httpCode int, msg string) {
 
 	c.logError(httpCode)
 
+	if data, ok := c.wrap.Data.(Versioned); ok && isSuccessHttpCode(httpCode) {
+		c.Header("ETag", ETag(data.GetVersion()))
+	}
+
 	c.JSON(httpCode, c.wrap)
 	c.Abort()
 }
@@ -207,6 +226,36 @@ func (c *Context) WithData(data interface{}) *Context {
 	return c
 }
 
+// Versioned is response data with a version, sent as its ETag for the If-Match of the next write
+type Versioned interface {
+	GetVersion() uint
+}
+
+// ETag returns the strong entity tag of the version
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// IfMatch returns the version of the If-Match header, the ETag of a Versioned response.
+// It is nil without the header or with the * wildcard, which matches any version.
+func (c *Context) IfMatch() (*uint, error) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return nil, nil
+	}
+
+	if len(ifMatch) < 2 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		return nil, fmt.Errorf("invalid If-Match %s", ifMatch)
+	}
+	version, err := strconv.ParseUint(ifMatch[1:len(ifMatch)-1], 10, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match %s", ifMatch)
+	}
+
+	v := uint(version)
+	return &v, nil
+}
+
 // WithError set error
 func (c *Context) WithError(err error) *Context {
 	c.err = err
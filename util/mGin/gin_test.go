@@ -0,0 +1,65 @@
+package mGin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVersioned struct {
+	Version uint `json:"version"`
+}
+
+func (v testVersioned) GetVersion() uint {
+	return v.Version
+}
+
+func newTestContext(ifMatch string) (*Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+	if ifMatch != "" {
+		c.Request.Header.Set("If-Match", ifMatch)
+	}
+	return NewContext(c), w
+}
+
+func TestIfMatch(t *testing.T) {
+	for _, ifMatch := range []string{"", "*"} {
+		ctx, _ := newTestContext(ifMatch)
+		version, err := ctx.IfMatch()
+		assert.NoError(t, err)
+		assert.Nil(t, version, ifMatch)
+	}
+
+	ctx, _ := newTestContext(ETag(3))
+	version, err := ctx.IfMatch()
+	assert.NoError(t, err)
+	if assert.NotNil(t, version) {
+		assert.Equal(t, uint(3), *version)
+	}
+
+	for _, ifMatch := range []string{`3`, `W/"3"`, `"a"`, `"`, `"1", "2"`} {
+		ctx, _ := newTestContext(ifMatch)
+		_, err := ctx.IfMatch()
+		assert.Error(t, err, ifMatch)
+	}
+}
+
+func TestResponseETag(t *testing.T) {
+	ctx, w := newTestContext("")
+	ctx.WithData(&testVersioned{Version: 2}).Response(http.StatusOK, "")
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	ctx, w = newTestContext("")
+	ctx.WithData([]testVersioned{{Version: 2}}).Response(http.StatusOK, "")
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	ctx, w = newTestContext("")
+	ctx.WithData(testVersioned{Version: 2}).Response(http.StatusBadRequest, "")
+	assert.Empty(t, w.Header().Get("ETag"))
+}
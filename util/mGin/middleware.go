@@ -2,6 +2,8 @@ package mGin
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 
@@ -34,6 +36,26 @@ func RequestBodyToContextMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RequestIDMiddleware keeps the request ID of the gateway, or generates one, and echoes it in the response
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderKeyRequestID)
+		if requestID == "" {
+			requestID = c.GetHeader(HeaderKeyKongRequestID)
+		}
+		if requestID == "" || len(requestID) > 64 {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		c.Set(ContextKeyRequestID, requestID)
+		c.Writer.Header().Set(HeaderKeyRequestID, requestID)
+
+		c.Next()
+	}
+}
+
 // reference: go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin@v0.28.0/gintrace.go
 func TracingMiddleware(service string) gin.HandlerFunc {
 	cfg := tracing.NewConfig(
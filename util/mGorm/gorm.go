@@ -3,6 +3,7 @@ package mGorm
 import (
 	"reflect"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -126,6 +127,27 @@ func (db *DB) WhereWithNumberFilter(column string, filter *filters.NumberFilter,
 	return db
 }
 
+// WhereWithTimestampFilter where with timestamp filter, in unix seconds
+func (db *DB) WhereWithTimestampFilter(column string, filter *filters.TimestampFilter, operator string) *DB {
+	if filter == nil || len(column) == 0 {
+		return db
+	}
+	if filter.After != nil {
+		db.DB = db.WhereWithOp(operator, column+" > ?", time.Unix(*filter.After, 0))
+	}
+	if filter.Before != nil {
+		db.DB = db.WhereWithOp(operator, column+" < ?", time.Unix(*filter.Before, 0))
+	}
+	if filter.On != nil {
+		db.DB = db.WhereWithOp(operator, column+" = ?", time.Unix(*filter.On, 0))
+	}
+	if filter.Between != nil && len(filter.Between) == 2 {
+		db.DB = db.WhereWithOp(operator, column+" BETWEEN ? AND ?", time.Unix(filter.Between[0], 0), time.Unix(filter.Between[1], 0))
+	}
+
+	return db
+}
+
 // WhereWithBoolFilter where with bool filter
 func (db *DB) WhereWithBoolFilter(column string, filter *filters.BooleanFilter, operator string) *DB {
 	if filter == nil || len(column) == 0 {
@@ -0,0 +1,15 @@
+package viewModel
+
+import "github.com/a5932016/go-ddd-example/model"
+
+type Announcement struct {
+	Title   string `json:"title" binding:"required,max=128"`
+	Content string `json:"content" binding:"max=65535"`
+}
+
+func (a Announcement) ToEntity() model.Announcement {
+	return model.Announcement{
+		Title:   model.NormalizeSpaces(a.Title),
+		Content: a.Content,
+	}
+}
@@ -0,0 +1,10 @@
+package viewModel
+
+import "github.com/a5932016/go-ddd-example/model"
+
+type CreateAPIKey struct {
+	Name   string              `json:"name" binding:"required,max=64"`
+	Scopes []model.APIKeyScope `json:"scopes" binding:"required,min=1,dive"`
+	// ExpiresInDays is the key lifetime, the key never expires when omitted
+	ExpiresInDays *uint `json:"expiresInDays" binding:"omitempty,min=1,max=3650"`
+}
@@ -0,0 +1,24 @@
+package viewModel
+
+type CreateDivision struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateDivision struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type UpdateDivisionUsers struct {
+	UserIDs []uint `json:"userIds" binding:"required"`
+}
+
+type AssignDivisionRole struct {
+	UserID uint   `json:"userId" binding:"required"`
+	Role   string `json:"role" binding:"required,alphanum,max=32"`
+}
+
+type AddRoleInheritance struct {
+	Parent string `json:"parent" binding:"required,alphanum,max=32"`
+}
@@ -2,3 +2,16 @@ package viewModel
 
 type User struct {
 }
+
+type CreateUser struct {
+	Email    string `json:"email" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	IsRoot   bool   `json:"isRoot"`
+}
+
+type UpdateUser struct {
+	Email  *string `json:"email"`
+	Name   *string `json:"name"`
+	IsRoot *bool   `json:"isRoot"`
+}
@@ -32,7 +32,10 @@ func InitRouter(appFs afero.Fs, mySqlC *gorm.DB, redisC *redis.Client) (router.H
 		repositoryProvider,
 		usecaseProvider,
 		permissionsHandler,
-		sessionRedisProviderManager,
+		passwordPolicyProvider,
+		passwordHasherProvider,
+		sessionProviderManager,
+		jwtSignerProvider,
 		router.NewRouter,
 	)
 	return router.Handler{}, nil
@@ -14,7 +14,6 @@ import (
 	"github.com/a5932016/go-ddd-example/singleton/entity"
 	"github.com/a5932016/go-ddd-example/singleton/entityUsecase"
 	"github.com/a5932016/go-ddd-example/singleton/session"
-	redis3 "github.com/a5932016/go-ddd-example/singleton/session/provider/redis"
 	"github.com/a5932016/go-ddd-example/usecase"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/afero"
@@ -45,21 +44,36 @@ func InitRouter(appFs afero.Fs, mySqlC *gorm.DB, redisC2 *redis.Client) (router.
 	if err != nil {
 		return router.Handler{}, err
 	}
-	perRepository, err := perRepoProvider(mySqlC)
+	perRepository, err := perRepoWithWatcherProvider(mySqlC, memRepository)
 	if err != nil {
 		return router.Handler{}, err
 	}
 	fsRepository := fsRepoProvider(appFs)
-	maxLifeTime := _sessionRedisProviderMaxLifeTime()
-	redisProvider := redis3.NewRedisProvider(memRepository, maxLifeTime)
-	sessionName := _sessionRedisProviderSessionName()
-	manager := session.NewManager(redisProvider, sessionName, maxLifeTime)
+	notifier := notifierProvider(appFs)
+	maxLifeTime := _sessionProviderMaxLifeTime()
+	provider := _sessionProvider(memRepository, maxLifeTime)
+	sessionName := _sessionProviderSessionName()
+	maxSessions := _sessionProviderMaxSessions()
+	manager := session.NewManager(provider, sessionName, maxLifeTime, maxSessions)
+	signer, err := jwtSignerProvider()
+	if err != nil {
+		return router.Handler{}, err
+	}
+	oidcProvider := oidcProviderProvider()
+	passwordPolicy, err := passwordPolicyProvider()
+	if err != nil {
+		return router.Handler{}, err
+	}
+	hasher, err := passwordHasherProvider()
+	if err != nil {
+		return router.Handler{}, err
+	}
 	modelPermissionsHandler, err := permissionsHandler()
 	if err != nil {
 		return router.Handler{}, err
 	}
-	handlerConstructor := usecase.NewHandler(dbRepository, memRepository, perRepository, fsRepository, manager, modelPermissionsHandler)
+	handlerConstructor := usecase.NewHandler(dbRepository, memRepository, perRepository, fsRepository, notifier, manager, signer, oidcProvider, passwordPolicy, hasher, modelPermissionsHandler)
 	entityUseCase := entityUsecase.NewEntityUseCase(dbRepository)
-	handler := router.NewRouter(handlerConstructor, entityUseCase, memRepository, perRepository, manager)
+	handler := router.NewRouter(handlerConstructor, entityUseCase, v, memRepository, perRepository, manager)
 	return handler, nil
 }
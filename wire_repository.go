@@ -5,18 +5,23 @@ import (
 	"github.com/spf13/afero"
 	"gorm.io/gorm"
 
+	"github.com/a5932016/go-ddd-example/config"
 	"github.com/a5932016/go-ddd-example/repository"
 	"github.com/a5932016/go-ddd-example/repository/casbin"
 	"github.com/a5932016/go-ddd-example/repository/fs"
 	"github.com/a5932016/go-ddd-example/repository/mysql"
+	"github.com/a5932016/go-ddd-example/repository/notifier"
+	"github.com/a5932016/go-ddd-example/repository/oidc"
 	"github.com/a5932016/go-ddd-example/repository/redis"
 )
 
 var repositoryProvider = wire.NewSet(
 	memRepoProvider,
 	dbRepoProvider,
-	perRepoProvider,
+	perRepoWithWatcherProvider,
 	fsRepoProvider,
+	notifierProvider,
+	oidcProviderProvider,
 )
 
 var (
@@ -34,6 +39,54 @@ func perRepoProvider(db *gorm.DB) (*casbin.PERRepository, error) {
 	return casbin.NewPERRepository(db, "casbin.conf", "casbin_rules")
 }
 
+// perRepoWithWatcherProvider keeps the policies of every instance in sync through redis
+func perRepoWithWatcherProvider(db *gorm.DB, memRepo repository.MemRepository) (*casbin.PERRepository, error) {
+	perRepo, err := perRepoProvider(db)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := casbin.NewRedisWatcher(memRepo, casbin.DefaultWatcherChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := perRepo.SetWatcher(watcher); err != nil {
+		return nil, err
+	}
+
+	return perRepo, nil
+}
+
 func fsRepoProvider(fsLib afero.Fs) fs.FSRepository {
 	return fs.NewFSRepository(fsLib)
 }
+
+func notifierProvider(fsLib afero.Fs) notifier.Notifier {
+	if config.Env.Notifier.Driver == "smtp" {
+		return notifier.NewSMTPNotifier(
+			config.Env.Notifier.SMTPHost,
+			config.Env.Notifier.SMTPPort,
+			config.Env.Notifier.SMTPUser,
+			config.Env.Notifier.SMTPPassword,
+			config.Env.Notifier.From,
+		)
+	}
+	return notifier.NewFileNotifier(fsLib, config.Env.Notifier.FilePath)
+}
+
+// oidcProviderProvider returns the single sign-on identity provider, and none without an issuer URL
+func oidcProviderProvider() *oidc.Provider {
+	if config.Env.OIDC.IssuerURL == "" {
+		return nil
+	}
+	return oidc.NewProvider(
+		config.Env.OIDC.IssuerURL,
+		config.Env.OIDC.ClientID,
+		config.Env.OIDC.ClientSecret,
+		config.Env.OIDC.RedirectURL,
+		config.Env.OIDC.Scopes,
+		config.Env.OIDC.GroupsClaim,
+		nil,
+	)
+}
@@ -1,38 +1,119 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/a5932016/go-ddd-example/config"
 	"github.com/a5932016/go-ddd-example/model"
+	"github.com/a5932016/go-ddd-example/repository"
+	"github.com/a5932016/go-ddd-example/repository/mysql"
 	"github.com/a5932016/go-ddd-example/singleton/entity"
-	"github.com/a5932016/go-ddd-example/singleton/entity/eGorm"
 	"github.com/a5932016/go-ddd-example/singleton/entityUsecase"
 	"github.com/a5932016/go-ddd-example/singleton/session"
+	memoryProvider "github.com/a5932016/go-ddd-example/singleton/session/provider/memory"
 	redisProvider "github.com/a5932016/go-ddd-example/singleton/session/provider/redis"
+	"github.com/a5932016/go-ddd-example/util/hasher"
+	"github.com/a5932016/go-ddd-example/util/jwt"
+	"github.com/a5932016/go-ddd-example/viewModel"
 	"github.com/google/wire"
-	"gorm.io/gorm"
+	"github.com/pkg/errors"
 )
 
-func _sessionRedisProviderSessionName() session.SessionName {
+func _sessionProviderSessionName() session.SessionName {
 	return session.SessionName(config.Env.SessionAuth.Name)
 }
 
-func _sessionRedisProviderMaxLifeTime() session.MaxLifeTime {
+func _sessionProviderMaxLifeTime() session.MaxLifeTime {
 	return session.MaxLifeTime(config.Env.SessionAuth.MaxLifeTime)
 }
 
+func _sessionProviderMaxSessions() session.MaxSessions {
+	return session.MaxSessions(config.Env.SessionAuth.MaxSessions)
+}
+
+// _sessionProvider selects the session store, the memory one lets the service run without redis sessions
+func _sessionProvider(memRepo repository.MemRepository, maxLifeTime session.MaxLifeTime) session.Provider {
+	if config.Env.SessionAuth.Provider == "memory" {
+		return memoryProvider.NewMemoryProvider(maxLifeTime)
+	}
+	return redisProvider.NewRedisProvider(memRepo, maxLifeTime)
+}
+
 var (
-	sessionRedisProviderManager = wire.NewSet(
-		_sessionRedisProvider,
-		_sessionRedisProviderSessionName,
-		_sessionRedisProviderMaxLifeTime,
+	sessionProviderManager = wire.NewSet(
+		_sessionProvider,
+		_sessionProviderSessionName,
+		_sessionProviderMaxLifeTime,
+		_sessionProviderMaxSessions,
 		session.NewManager,
 	)
-	_sessionRedisProvider = wire.NewSet(
-		redisProvider.NewRedisProvider,
-		wire.Bind(new(session.Provider), new(*redisProvider.RedisProvider)),
-	)
 )
 
+// jwtSignerProvider loads the access token key of the jwt strategy, and none for the session strategy
+func jwtSignerProvider() (*jwt.Signer, error) {
+	if config.Env.Auth.Strategy != "jwt" {
+		return nil, nil
+	}
+
+	switch config.Env.JWT.Algorithm {
+	case jwt.HS256:
+		return jwt.NewHS256Signer([]byte(config.Env.JWT.Secret))
+	case jwt.RS256:
+		content, err := os.ReadFile(filepath.Clean(config.Env.JWT.PrivateKeyPath))
+		if err != nil {
+			return nil, errors.Wrap(err, "read jwt private key")
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyPEM(content)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse jwt private key")
+		}
+		return jwt.NewRS256Signer(privateKey, config.Env.JWT.KeyID), nil
+	}
+	return nil, errors.Errorf("unsupported jwt algorithm %s", config.Env.JWT.Algorithm)
+}
+
+// passwordPolicyProvider builds the password policy with its common password blocklist
+func passwordPolicyProvider() (model.PasswordPolicy, error) {
+	policy := model.PasswordPolicy{
+		MinLength:     config.Env.Password.MinLength,
+		MaxLength:     config.Env.Password.MaxLength,
+		RequireUpper:  config.Env.Password.RequireUpper,
+		RequireLower:  config.Env.Password.RequireLower,
+		RequireDigit:  config.Env.Password.RequireDigit,
+		RequireSymbol: config.Env.Password.RequireSymbol,
+		HistorySize:   config.Env.Password.HistorySize,
+	}
+
+	blocklist, err := os.Open(filepath.Clean(config.Env.Password.BlocklistPath))
+	if err != nil {
+		return model.PasswordPolicy{}, errors.Wrap(err, "open password blocklist")
+	}
+	defer blocklist.Close()
+
+	if err := policy.LoadBlocklist(blocklist); err != nil {
+		return model.PasswordPolicy{}, err
+	}
+	return policy, nil
+}
+
+// passwordHasherProvider hashes with the configured algorithm, and still verifies the hashes of the other one
+func passwordHasherProvider() (*hasher.Hasher, error) {
+	argon2id := hasher.DefaultArgon2id
+	argon2id.Memory = config.Env.Password.Argon2Memory
+	argon2id.Iterations = config.Env.Password.Argon2Iterations
+	argon2id.Parallelism = config.Env.Password.Argon2Parallelism
+	bcrypt := hasher.Bcrypt{Cost: config.Env.Password.BcryptCost}
+
+	switch config.Env.Password.HashAlgorithm {
+	case "argon2id":
+		return hasher.New(argon2id, bcrypt), nil
+	case "bcrypt":
+		return hasher.New(bcrypt, argon2id), nil
+	}
+	return nil, errors.Errorf("unsupported password hash algorithm %s", config.Env.Password.HashAlgorithm)
+}
+
 func permissionsHandler() (model.PermissionsHandler, error) {
 	return model.NewPermissionsHandler("resource_action_rules.json")
 }
@@ -47,12 +128,19 @@ var (
 	)
 )
 
-func _registerEntities() []entity.RegisterOpt[any] {
-	return []entity.RegisterOpt[any]{{}}
-}
-
-func _wrapListQueryFunc[T any](fn func(db *gorm.DB, opt T) *gorm.DB) eGorm.ListQueryFunc {
-	return func(db *gorm.DB, listQueryOption any) *gorm.DB {
-		return fn(db, listQueryOption.(T))
+// _registerEntities lists the entities served by the generic entity routes
+func _registerEntities() []entity.Registration {
+	return []entity.Registration{
+		entity.Register(entity.Definition[model.Announcement, model.AnnouncementFilter, viewModel.Announcement]{
+			Name:      "announcement",
+			Resource:  model.ResourceAnnouncement,
+			ListQuery: mysql.AnnouncementListQuery,
+			SortKeys: map[string]string{
+				"id":        "id",
+				"title":     "title",
+				"createdAt": "created_at",
+				"updatedAt": "updated_at",
+			},
+		}),
 	}
 }